@@ -0,0 +1,129 @@
+// Command benchcheck compares the output of "go test -bench" against the
+// checked-in baseline and fails if any benchmark regressed beyond the
+// tolerance.
+//
+// Usage:
+//
+//	go test -run '^$' -bench . -benchmem ./... | benchcheck -baseline benchmarks/baseline.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// result holds the measurements of a single benchmark.
+type result struct {
+	nsPerOp     float64
+	allocsPerOp float64
+	hasAllocs   bool
+}
+
+// procsSuffix matches the GOMAXPROCS suffix of the benchmark names.
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// parse reads the "go test -bench" output. Benchmarks are keyed by their
+// package and name, without the GOMAXPROCS suffix.
+func parse(r io.Reader) (map[string]result, error) {
+	results := make(map[string]result)
+	pkg := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "pkg:" {
+			pkg = fields[1]
+			continue
+		}
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		r := result{}
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed benchmark line %q", scanner.Text())
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				r.nsPerOp = value
+			case "allocs/op":
+				r.allocsPerOp = value
+				r.hasAllocs = true
+			}
+		}
+		results[pkg+"."+procsSuffix.ReplaceAllString(fields[0], "")] = r
+	}
+	return results, scanner.Err()
+}
+
+// regressed tells whether the current value exceeds the baseline by more
+// than the tolerance.
+func regressed(baseline float64, current float64, tolerance float64) bool {
+	return current > baseline*(1+tolerance) && current-baseline >= 1
+}
+
+// compare writes the comparison of each current benchmark against the
+// baseline and returns the number of regressions.
+func compare(w io.Writer, baseline map[string]result, current map[string]result, timeTolerance float64, allocTolerance float64) int {
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	regressions := 0
+	for _, name := range names {
+		cur := current[name]
+		base, ok := baseline[name]
+		if !ok {
+			fmt.Fprintf(w, "%s: %.0f ns/op (not in baseline)\n", name, cur.nsPerOp)
+			continue
+		}
+		verdict := "ok"
+		if regressed(base.nsPerOp, cur.nsPerOp, timeTolerance) ||
+			(base.hasAllocs && cur.hasAllocs && regressed(base.allocsPerOp, cur.allocsPerOp, allocTolerance)) {
+			verdict = "REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(w, "%s: %.0f -> %.0f ns/op (%+.1f%%), %.0f -> %.0f allocs/op: %s\n", name,
+			base.nsPerOp, cur.nsPerOp, 100*(cur.nsPerOp-base.nsPerOp)/base.nsPerOp,
+			base.allocsPerOp, cur.allocsPerOp, verdict)
+	}
+	return regressions
+}
+
+func main() {
+	baselinePath := flag.String("baseline", "benchmarks/baseline.txt", "Path of the baseline \"go test -bench\" output.")
+	timeTolerance := flag.Float64("time-tolerance", 0.5, "Allowed increase of ns/op as a fraction of the baseline.")
+	allocTolerance := flag.Float64("alloc-tolerance", 0.1, "Allowed increase of allocs/op as a fraction of the baseline.")
+	flag.Parse()
+
+	file, err := os.Open(*baselinePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(66)
+	}
+	baseline, err := parse(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(65)
+	}
+	current, err := parse(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(65)
+	}
+
+	if regressions := compare(os.Stdout, baseline, current, *timeTolerance, *allocTolerance); regressions > 0 {
+		fmt.Fprintf(os.Stderr, "%d benchmark(s) regressed\n", regressions)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const benchOutput = `goos: linux
+pkg: parking_lot/parser
+BenchmarkNextCommand-8   	 6776460	       174.4 ns/op	     157 B/op	       4 allocs/op
+pkg: parking_lot/processor
+BenchmarkProcess   	       1	3921382614 ns/op	   5.35 MB/s	1027702648 B/op	18883007 allocs/op
+PASS
+`
+
+func TestParse(t *testing.T) {
+	results, err := parse(strings.NewReader(benchOutput))
+	if err != nil {
+		t.Fatalf("parse() Error %v", err)
+	}
+	r, ok := results["parking_lot/parser.BenchmarkNextCommand"]
+	if !ok || r.nsPerOp != 174.4 || r.allocsPerOp != 4 {
+		t.Errorf("parse() got %+v", results)
+	}
+	if r := results["parking_lot/processor.BenchmarkProcess"]; r.allocsPerOp != 18883007 {
+		t.Errorf("parse() got %+v", r)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := map[string]result{
+		"p.BenchmarkA": {nsPerOp: 100, allocsPerOp: 4, hasAllocs: true},
+		"p.BenchmarkB": {nsPerOp: 100, allocsPerOp: 0, hasAllocs: true},
+	}
+	current := map[string]result{
+		"p.BenchmarkA": {nsPerOp: 140, allocsPerOp: 4, hasAllocs: true},
+		"p.BenchmarkB": {nsPerOp: 90, allocsPerOp: 1, hasAllocs: true},
+		"p.BenchmarkC": {nsPerOp: 10},
+	}
+	out := bytes.Buffer{}
+	if regressions := compare(&out, baseline, current, 0.5, 0.1); regressions != 1 {
+		t.Errorf("compare() got %d regressions want %d\n%s", regressions, 1, out.String())
+	}
+	if !strings.Contains(out.String(), "p.BenchmarkB: 100 -> 90 ns/op (-10.0%), 0 -> 1 allocs/op: REGRESSION") {
+		t.Errorf("compare() got %s", out.String())
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"parking_lot/common"
+	"parking_lot/parser"
+	"parking_lot/processor"
+)
+
+// check validates the input files without executing them against the real state.
+func (c *cli) check(args []string) int {
+	flags := c.newFlagSet("check", "check [flags] input-file ...")
+	options := lotOptions{}
+	flags.StringVar(&options.state, "state", "",
+		"Path of the file holding the persistent state. Simulation starts from this state. It is never modified.")
+	options.registerExecutor(flags)
+	if code, ok := c.parseFlags(flags, args); !ok {
+		return code
+	}
+	return c.checkInputs(options, flags.Args())
+}
+
+// checkInputs validates the inputs in order against a scratch state. Every
+// problem found is reported to stderr along with its input name and line
+// number. Returns the exit code of the first problem if any was found.
+func (c *cli) checkInputs(options lotOptions, paths []string) int {
+	if len(paths) == 0 {
+		return c.fail(errIncorrectUsage)
+	}
+
+	executorOptions, err := options.executorOptions()
+	if err != nil {
+		return c.fail(err)
+	}
+	checker := processor.NewChecker(executorOptions...)
+	if options.state != "" {
+		snapshot, err := readSnapshotFile(options.state)
+		if err != nil {
+			return c.fail(err)
+		}
+		if err := checker.Restore(snapshot); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	inputs, err := c.openInputs(paths)
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeInputs(inputs)
+
+	var first error
+	problems := 0
+	for _, in := range inputs {
+		tokenizer := parser.NewTokenizer(in.reader)
+		diagnostics, err := checker.Check(&tokenizer)
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintf(c.stderr, "%s:%d: %s\n", in.name, diagnostic.Line, diagnostic.Err.Error())
+			if first == nil {
+				first = diagnostic.Err
+			}
+		}
+		problems += len(diagnostics)
+		if err != nil {
+			return c.fail(err)
+		}
+	}
+
+	if problems > 0 {
+		fmt.Fprintf(c.stderr, "%d problem(s) found\n", problems)
+		return common.ExitCode(first)
+	}
+	fmt.Fprintf(c.stderr, "%s\n", "No problems found")
+	return common.ExitCodeOK
+}
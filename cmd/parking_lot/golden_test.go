@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Regenerate the golden files from the actual output.")
+
+// goldenDir holds the end-to-end cases. Each case is an input file
+// <name>.txt with the commands, along with the golden files of its output
+// <name>.golden (non-interactive mode) and <name>.interactive.golden
+// (interactive mode). An optional <name>.args file lists the extra flags,
+// separated by whitespace.
+const goldenDir = "testdata/golden"
+
+// goldenCase is an end-to-end case discovered under goldenDir.
+type goldenCase struct {
+	name  string
+	input string
+	args  []string
+}
+
+func discoverGoldenCases(t *testing.T) []goldenCase {
+	inputs, err := filepath.Glob(filepath.Join(goldenDir, "*.txt"))
+	if err != nil {
+		t.Fatalf("Glob() got %v", err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("No cases found in %s", goldenDir)
+	}
+
+	cases := make([]goldenCase, 0, len(inputs))
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		c := goldenCase{name: name, input: input}
+		args, err := ioutil.ReadFile(filepath.Join(goldenDir, name+".args"))
+		if err == nil {
+			c.args = strings.Fields(string(args))
+		} else if !os.IsNotExist(err) {
+			t.Fatalf("ReadFile() got %v", err)
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+// runGolden runs the CLI and renders the golden output. Stdout is rendered
+// as is, followed by stderr and the exit code only if the run failed or
+// wrote to stderr, so that the output of a clean run reads like the terminal.
+func runGolden(args []string, stdin []byte) []byte {
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, bytes.NewReader(stdin), &stdout, &stderr)
+	if code != 0 || stderr.Len() > 0 {
+		fmt.Fprintf(&stdout, "--- stderr ---\n%s--- exit code: %d ---\n", stderr.String(), code)
+	}
+	return stdout.Bytes()
+}
+
+func TestGolden(t *testing.T) {
+	for _, c := range discoverGoldenCases(t) {
+		input, err := ioutil.ReadFile(c.input)
+		if err != nil {
+			t.Fatalf("ReadFile() got %v", err)
+		}
+		modes := []struct {
+			name   string
+			golden string
+			args   []string
+			stdin  []byte
+		}{
+			{"non-interactive", c.name + ".golden", append(append([]string{}, c.args...), c.input), nil},
+			{"interactive", c.name + ".interactive.golden", c.args, input},
+		}
+		for _, mode := range modes {
+			t.Run(c.name+"/"+mode.name, func(t *testing.T) {
+				got := runGolden(mode.args, mode.stdin)
+				path := filepath.Join(goldenDir, mode.golden)
+				if *update {
+					if err := ioutil.WriteFile(path, got, 0644); err != nil {
+						t.Fatalf("WriteFile() got %v", err)
+					}
+					return
+				}
+				want, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatalf("ReadFile() got %v, run go test with -update to create the golden file", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("Output differs from %s (-want +got), run go test with -update to accept:\n%s",
+						path, lineDiff(string(want), string(got)))
+				}
+			})
+		}
+	}
+}
+
+// lineDiff renders the line by line difference of want and got based on
+// their longest common subsequence. Common lines are prefixed with spaces,
+// missing lines with "-" and unexpected lines with "+".
+func lineDiff(want string, got string) string {
+	a := strings.SplitAfter(want, "\n")
+	b := strings.SplitAfter(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	line := func(prefix string, text string) {
+		if text == "" {
+			return
+		}
+		if !strings.HasSuffix(text, "\n") {
+			text += "\\ No newline at end\n"
+		}
+		diff.WriteString(prefix + text)
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			line("  ", a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			line("- ", a[i])
+			i++
+		default:
+			line("+ ", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		want string
+		got  string
+		diff string
+	}{
+		{"a\nb\n", "a\nb\n", "  a\n  b\n"},
+		{"a\nb\nc\n", "a\nc\n", "  a\n- b\n  c\n"},
+		{"a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"},
+		{"a\n", "a", "- a\n+ a\\ No newline at end\n"},
+	}
+	for _, test := range tests {
+		if diff := lineDiff(test.want, test.got); diff != test.diff {
+			t.Errorf("lineDiff(%q, %q) got %q want %q", test.want, test.got, diff, test.diff)
+		}
+	}
+}
@@ -4,67 +4,88 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"parking_lot/dao"
-	"parking_lot/parser"
-	"parking_lot/processor"
-	"sync"
+	"parking_lot/common"
 )
 
-func main() {
-	allocator := processor.NewNearestAllocator()
-	storage := dao.InMemoryStorage{}
-	mu := sync.Mutex{}
+// version of the program. Overridden at the build time with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
 
-	argsWithoutProg := os.Args[1:]
-	var fileArgument string
+var (
+	errIncorrectUsage = common.NewError("ERR_INCORRECT_USAGE", common.CategoryUsage,
+		"incorrect usage, run parking_lot --help for the usage")
+	errInputNotReadable = common.NewError("ERR_INPUT_NOT_READABLE", common.CategoryNotFound,
+		"input file can not be opened")
+)
 
-	if len(argsWithoutProg) > 1 {
-		fmt.Fprintf(os.Stderr, "%s", "Incorrect usage")
-		os.Exit(-1)
-	}
+const usage = `Usage:
+  parking_lot [run] [flags] [input-file ...]
+  parking_lot check [flags] input-file ...
+  parking_lot replay [flags] input-file ...
+  parking_lot serve [flags]
+  parking_lot version
+  parking_lot help
 
-	if len(argsWithoutProg) == 1 {
-		fileArgument = argsWithoutProg[0]
-	}
+Subcommands:
+  run     Execute commands. Without input files, commands are read interactively
+          from stdin. Input files are processed in order, "-" reads from stdin.
+          This is the default subcommand.
+  check   Validate input files without executing them against the real state.
+  replay  Execute command logs against a fresh parking lot, continuing past
+          failing lines, and print a summary. With --session, re-execute a
+          session recorded with run --record and report differing responses.
+  serve   Execute commands posted over HTTP to /commands.
+  version Print the version.
 
-	if fileArgument != "" {
-		inputFile, err := os.OpenFile(fileArgument, os.O_RDONLY, os.ModePerm)
-		if err != nil {
-			os.Exit(-1)
-		}
-		tokenizer := parser.NewTokenizer(inputFile)
-		runNonInteractive(&tokenizer, &allocator, &storage, &mu)
-	} else {
-		tokenizer := parser.NewTokenizer(os.Stdin)
-		runInteractive(&tokenizer, &allocator, &storage, &mu)
-	}
+Run "parking_lot <subcommand> --help" for the flags of a subcommand.
+
+Exit codes:
+  0   Success.
+  64  Usage error (Eg: unknown command, incorrect number of arguments).
+  65  Validation error (Eg: invalid lot size, slot beyond the size of the lot).
+  66  Not found error (Eg: leaving an empty slot, unreadable input file).
+  69  Capacity error (Eg: slot already occupied, closed or held).
+  70  Internal error.
+`
+
+func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
-// runInteractive inits the program in the interactive mode.
-func runInteractive(tokenizer *parser.Tokenizer, allocator processor.Allocator, storage dao.Storage, mu *sync.Mutex) {
-	for {
-		fmt.Printf("%s", "$ ") // Print prompt
-		out, err := processor.Process(tokenizer, mu, allocator, storage)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-		}
-		fmt.Printf("%s", out)
-	}
+// cli holds the standard streams used by the subcommands.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
 }
 
-// runInteractive inits the program in the non-interactive mode. In non-interactive mode,
-// any errors processing the input will terminate the program.
-func runNonInteractive(tokenizer *parser.Tokenizer, allocator processor.Allocator, storage dao.Storage, mu *sync.Mutex) {
-	for {
-		out, err := processor.Process(tokenizer, mu, allocator, storage)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
-			os.Exit(-2)
+// runCLI dispatches the arguments to the subcommand and returns the exit code.
+// Arguments not starting with a subcommand are handled by the run subcommand.
+func runCLI(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			return c.run(args[1:])
+		case "check":
+			return c.check(args[1:])
+		case "replay":
+			return c.replay(args[1:])
+		case "serve":
+			return c.serve(args[1:])
+		case "version", "--version", "-version":
+			fmt.Fprintf(stdout, "parking_lot %s\n", version)
+			return common.ExitCodeOK
+		case "help", "--help", "-help", "-h":
+			fmt.Fprint(stdout, usage)
+			return common.ExitCodeOK
 		}
-		fmt.Print(out)
 	}
+	return c.run(args)
+}
+
+// fail reports the error to stderr and returns its exit code.
+func (c *cli) fail(err error) int {
+	fmt.Fprintf(c.stderr, "%s\n", err.Error())
+	return common.ExitCode(err)
 }
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"parking_lot/palette"
+	"parking_lot/plate"
+	"parking_lot/processor"
+	"parking_lot/webhook"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lotOptions are the flags shared by the subcommands dealing with the
+// parking lot state.
+type lotOptions struct {
+	format      string
+	allocator   string
+	state       string
+	plateFormat string
+	palette     string
+	strictColor bool
+	overflow    string
+	waitlistCap int
+	strictWatch bool
+	stayLimits  string
+	grace       time.Duration
+	retention   time.Duration
+	// clock overrides the clock of the executor. Not a flag, set while
+	// recording and replaying the sessions.
+	clock processor.Clock
+
+	webhooks      string
+	webhookOutbox string
+	webhookDrain  time.Duration
+	overstayCheck time.Duration
+	// webhookLog receives the failed webhook deliveries. Not a flag.
+	webhookLog io.Writer
+}
+
+func (o *lotOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.format, "format", "text", "Output format: text or json.")
+	flags.StringVar(&o.allocator, "allocator", "nearest", "Slot allocation strategy: nearest, or bitmap for the very large lots.")
+	flags.StringVar(&o.state, "state", "",
+		"Path of the file holding the persistent state. Loaded on start if it exists and saved on exit.")
+	o.registerExecutor(flags)
+}
+
+// registerExecutor registers the flags affecting execution of the commands.
+func (o *lotOptions) registerExecutor(flags *flag.FlagSet) {
+	flags.StringVar(&o.plateFormat, "plate-format", "in,any",
+		"Comma separated list of accepted registration number formats, tried in order: in (Indian), any (permissive).")
+	flags.StringVar(&o.palette, "palette", "",
+		"Path of the colour palette file. Each line lists a colour and its synonyms (Eg: \"Grey: Gray\"). Defaults to the built-in palette.")
+	flags.BoolVar(&o.strictColor, "strict-colours", false, "Reject colours not present in the palette.")
+	flags.StringVar(&o.overflow, "overflow", "",
+		"Comma separated list of reserved slot categories allocated to cars without a permit once general slots run out.")
+	flags.IntVar(&o.waitlistCap, "waitlist-cap", 0,
+		"Maximum number of cars waitlisted while the parking lot is full. 0 disables the waitlist.")
+	flags.BoolVar(&o.strictWatch, "strict-watchlist", false,
+		"Refuse parking to the cars watchlisted with the blocked severity.")
+	flags.StringVar(&o.stayLimits, "stay-limits", "",
+		"Comma separated list of the maximum stays, for the lot or for a slot category (Eg: \"8h,general=4h,ev=90m\").")
+	flags.DurationVar(&o.grace, "reservation-grace", processor.DefaultReservationGrace,
+		"Time a reservation holds the capacity after its start before it is released as a no-show. 0 holds it for the whole window.")
+	flags.DurationVar(&o.retention, "history-retention", processor.DefaultHistoryRetention,
+		"Time the history of the point-in-time queries is kept for. 0 keeps the whole history.")
+}
+
+// registerNotifications registers the flags of the webhook notifications and
+// the overstay checks. Failed deliveries are reported to the log.
+func (o *lotOptions) registerNotifications(flags *flag.FlagSet, log io.Writer) {
+	flags.StringVar(&o.webhooks, "webhooks", "",
+		"Path of the webhook configuration file: JSON list of endpoints the parking lot events are posted to.")
+	flags.StringVar(&o.webhookOutbox, "webhook-outbox", "",
+		"Directory the pending webhook deliveries are persisted in until delivered. Defaults to memory only.")
+	flags.DurationVar(&o.webhookDrain, "webhook-drain", 5*time.Second,
+		"Maximum time to wait on exit for the pending webhook deliveries.")
+	flags.DurationVar(&o.overstayCheck, "overstay-check", time.Minute,
+		"Interval of the checks posting the overstay events to the webhooks. Used with --stay-limits.")
+	o.webhookLog = log
+}
+
+// executorOptions returns the processor options as per the flags.
+func (o *lotOptions) executorOptions() ([]processor.Option, error) {
+	plates, err := plate.Lookup(o.plateFormat)
+	if err != nil {
+		return nil, err
+	}
+	colors := palette.Default()
+	if o.palette != "" {
+		file, err := os.Open(o.palette)
+		if err != nil {
+			return nil, errInputNotReadable.WithDetail("%s", err.Error())
+		}
+		defer file.Close()
+		if colors, err = palette.Load(file); err != nil {
+			return nil, err
+		}
+	}
+	colors.SetStrict(o.strictColor)
+
+	overflow := make([]dao.SlotCategory, 0)
+	for _, name := range strings.Split(o.overflow, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		category, err := dao.ParseSlotCategory(name)
+		if err != nil {
+			return nil, err
+		}
+		overflow = append(overflow, category)
+	}
+	if o.waitlistCap < 0 {
+		return nil, processor.ErrWaitlistCapacityInvalid
+	}
+	limits, err := processor.ParseStayLimits(o.stayLimits)
+	if err != nil {
+		return nil, err
+	}
+	if o.grace < 0 {
+		return nil, processor.ErrReservationInvalid.WithDetail("reservation grace period %s must not be negative", o.grace)
+	}
+	if o.retention < 0 {
+		return nil, processor.ErrHistoryRetentionInvalid.WithDetail("history retention %s must not be negative", o.retention)
+	}
+
+	options := []processor.Option{
+		processor.WithPlateFormat(plates),
+		processor.WithPalette(colors),
+		processor.WithOverflow(overflow...),
+		processor.WithWaitlistCapacity(o.waitlistCap),
+		processor.WithWatchlistStrict(o.strictWatch),
+		processor.WithStayLimits(limits),
+		processor.WithReservationGrace(o.grace),
+		processor.WithHistoryRetention(o.retention),
+	}
+	if o.clock != nil {
+		options = append(options, processor.WithClock(o.clock))
+	}
+	return options, nil
+}
+
+// lot is the parking lot the subcommands operate on.
+type lot struct {
+	mu        sync.Mutex
+	executor  processor.Executor
+	formatter processor.Formatter
+	// dispatcher posts the events to the webhooks, if configured.
+	dispatcher *webhook.Dispatcher
+	drain      time.Duration
+	// stopChecks stops the overstay checks, if running.
+	stopChecks func()
+	// changed is set once a command that may change the state is executed.
+	changed bool
+}
+
+// newLot builds the parking lot as per the options. The persistent state is
+// loaded only if loadState is set.
+func (o *lotOptions) newLot(loadState bool) (*lot, error) {
+	formatter, err := processor.NewFormatter(o.format)
+	if err != nil {
+		return nil, err
+	}
+	allocator, err := processor.NewAllocator(o.allocator)
+	if err != nil {
+		return nil, err
+	}
+	executorOptions, err := o.executorOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	bus := events.NewBus()
+	executorOptions = append(executorOptions, processor.WithEvents(bus))
+
+	l := &lot{formatter: formatter, drain: o.webhookDrain}
+	l.executor = processor.NewExecutor(&l.mu, allocator, &dao.InMemoryStorage{}, executorOptions...)
+	if err := o.loadState(l, loadState); err != nil {
+		return nil, err
+	}
+	// Subscribed after the state is loaded so that restoring the state is
+	// not posted again on every start.
+	if o.webhooks != "" {
+		if l.dispatcher, err = o.newDispatcher(); err != nil {
+			return nil, err
+		}
+		bus.Subscribe(l.dispatcher.Handle)
+		if o.stayLimits != "" && o.overstayCheck > 0 {
+			l.startChecks(o.overstayCheck)
+		}
+	}
+	return l, nil
+}
+
+// startChecks checks for the overstays on every interval until close.
+func (l *lot) startChecks(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		l.executor.WatchOverstays(ticker.C, stop)
+		close(done)
+	}()
+	l.stopChecks = func() {
+		ticker.Stop()
+		close(stop)
+		<-done
+	}
+}
+
+// loadState restores the persistent state if loadState is set and the
+// state file exists.
+func (o *lotOptions) loadState(l *lot, loadState bool) error {
+	if !loadState || o.state == "" {
+		return nil
+	}
+	if _, err := os.Stat(o.state); os.IsNotExist(err) {
+		// State is saved on exit.
+		return nil
+	}
+	snapshot, err := readSnapshotFile(o.state)
+	if err != nil {
+		return err
+	}
+	return l.executor.Restore(snapshot)
+}
+
+// newDispatcher builds the dispatcher posting to the configured webhooks.
+func (o *lotOptions) newDispatcher() (*webhook.Dispatcher, error) {
+	file, err := os.Open(o.webhooks)
+	if err != nil {
+		return nil, errInputNotReadable.WithDetail("%s", err.Error())
+	}
+	defer file.Close()
+	endpoints, err := webhook.ReadConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	logOutput := o.webhookLog
+	if logOutput == nil {
+		logOutput = ioutil.Discard
+	}
+	return webhook.NewDispatcher(endpoints, webhook.WithOutbox(o.webhookOutbox),
+		webhook.WithLogger(log.New(logOutput, "webhook: ", 0)))
+}
+
+// close stops the overstay checks and waits for the pending webhook
+// deliveries up to the drain timeout. Deliveries still pending are left in
+// the outbox for the next run.
+func (l *lot) close() {
+	if l.stopChecks != nil {
+		l.stopChecks()
+	}
+	if l.dispatcher != nil {
+		l.dispatcher.Close(l.drain)
+	}
+}
+
+// saveState saves the state of the parking lot if the state file is set.
+// The snapshot is written to a temporary file in the same directory which
+// then replaces the state file, so a crash never leaves it half written.
+func (o *lotOptions) saveState(l *lot) error {
+	if o.state == "" {
+		return nil
+	}
+	file, err := ioutil.TempFile(filepath.Dir(o.state), filepath.Base(o.state)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := writeStateFile(file, l); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), o.state); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// writeStateFile writes the snapshot to the file, flushes it to the disk
+// and closes the file.
+func writeStateFile(file *os.File, l *lot) error {
+	if err := processor.WriteSnapshot(file, l.executor.Snapshot()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readSnapshotFile(path string) (processor.Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return processor.Snapshot{}, errInputNotReadable.WithDetail("%s", err.Error())
+	}
+	defer file.Close()
+	return processor.ReadSnapshot(file)
+}
+
+// input is a named input of the commands.
+type input struct {
+	name   string
+	reader io.Reader
+	closer io.Closer
+}
+
+// openInputs opens the input files in order. "-" stands for stdin.
+func (c *cli) openInputs(paths []string) ([]input, error) {
+	inputs := make([]input, 0, len(paths))
+	for _, path := range paths {
+		if path == "-" {
+			inputs = append(inputs, input{name: "<stdin>", reader: c.stdin})
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			closeInputs(inputs)
+			return nil, errInputNotReadable.WithDetail("%s", err.Error())
+		}
+		inputs = append(inputs, input{name: path, reader: file, closer: file})
+	}
+	return inputs, nil
+}
+
+func closeInputs(inputs []input) {
+	for _, in := range inputs {
+		if in.closer != nil {
+			in.closer.Close()
+		}
+	}
+}
+
+// newFlagSet builds a flag set for the subcommand printing its usage to stderr.
+func (c *cli) newFlagSet(name string, synopsis string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: parking_lot %s\n\nFlags:\n", synopsis)
+		flags.PrintDefaults()
+	}
+	return flags
+}
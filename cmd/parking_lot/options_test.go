@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunWebhooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	mu := sync.Mutex{}
+	posted := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Type string `json:"type"`
+			Slot int    `json:"slot"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		posted = append(posted, fmt.Sprintf("%s %d", body.Type, body.Slot))
+	}))
+	defer server.Close()
+
+	config := filepath.Join(dir, "webhooks.json")
+	endpoints := fmt.Sprintf(`[{"url": %q, "events": ["car_parked", "car_left", "lot_full"]}]`, server.URL)
+	if err := ioutil.WriteFile(config, []byte(endpoints), 0600); err != nil {
+		t.Fatalf("WriteFile() got %v", err)
+	}
+
+	input := "create_parking_lot 1\npark KA-01-HH-1234 White\nleave 1\n"
+	var stdout, stderr bytes.Buffer
+	args := []string{"run", "--webhooks", config, "--webhook-outbox", filepath.Join(dir, "outbox"), "-"}
+	if code := runCLI(args, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("run --webhooks got exit code %d: %s", code, stderr.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"car_parked 1", "lot_full 0", "car_left 1"}; !reflect.DeepEqual(posted, want) {
+		t.Errorf("run --webhooks posted %v want %v", posted, want)
+	}
+}
+
+func TestRunWebhooksConfigInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := filepath.Join(dir, "webhooks.json")
+	if err := ioutil.WriteFile(config, []byte(`[{"url": "localhost"}]`), 0600); err != nil {
+		t.Fatalf("WriteFile() got %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"run", "--webhooks", config, "-"}, strings.NewReader(""), &stdout, &stderr); code != 65 {
+		t.Errorf("run --webhooks got exit code %d want %d: %s", code, 65, stderr.String())
+	}
+}
+
+func TestRunStateSavedOnlyAfterChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	state := filepath.Join(dir, "lot.json")
+	run := func(input string) {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI([]string{"run", "--state", state, "-"}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+			t.Fatalf("run --state got exit code %d: %s", code, stderr.String())
+		}
+	}
+
+	run("create_parking_lot 2\npark KA-01-HH-1234 White\n")
+	saved, err := os.Stat(state)
+	if err != nil {
+		t.Fatalf("Stat() got %v", err)
+	}
+	// Marks the file so that a rewrite is detected regardless of the
+	// resolution of the modification time.
+	if err := os.Chtimes(state, saved.ModTime(), saved.ModTime().Add(-time.Hour)); err != nil {
+		t.Fatalf("Chtimes() got %v", err)
+	}
+	run("status\nstats\n")
+	if info, _ := os.Stat(state); !info.ModTime().Equal(saved.ModTime().Add(-time.Hour)) {
+		t.Errorf("run --state rewrote the state after the read-only commands")
+	}
+
+	run("leave 1\n")
+	if info, _ := os.Stat(state); info.ModTime().Equal(saved.ModTime().Add(-time.Hour)) {
+		t.Errorf("run --state did not save the state after leave")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("run --state left %d files in the directory want 1", len(files))
+	}
+}
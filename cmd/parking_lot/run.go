@@ -0,0 +1,221 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"parking_lot/common"
+	"parking_lot/parser"
+	"parking_lot/processor"
+)
+
+// parseFlags parses the arguments. Returns false along with the exit code if
+// the program should exit (Eg: on --help or on incorrect flags).
+func (c *cli) parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		return common.ExitCodeOK, false
+	} else if err != nil {
+		return common.ExitCode(errIncorrectUsage), false
+	}
+	return common.ExitCodeOK, true
+}
+
+// run executes the commands from the input files in order. Without input
+// files, commands are read from stdin in the interactive mode.
+func (c *cli) run(args []string) int {
+	flags := c.newFlagSet("run", "[run] [flags] [input-file ...]")
+	options := lotOptions{}
+	options.register(flags)
+	options.registerNotifications(flags, c.stderr)
+	keepGoing := flags.Bool("keep-going", false,
+		"Report failing lines and continue processing the input. Prints a summary at the end.")
+	check := flags.Bool("check", false, "Validate the input files instead of executing them. Same as check subcommand.")
+	record := flags.String("record", "",
+		"Path of the file the interactive session is recorded to: every command, its timestamp and response. See replay --session.")
+	if code, ok := c.parseFlags(flags, args); !ok {
+		return code
+	}
+	if *check {
+		return c.checkInputs(options, flags.Args())
+	}
+	if *record != "" && flags.NArg() > 0 {
+		fmt.Fprintf(c.stderr, "%s\n", errIncorrectUsage.WithDetail("--record is supported only in the interactive mode"))
+		return common.ExitCode(errIncorrectUsage)
+	}
+
+	var clock *sessionClock
+	if *record != "" {
+		clock = &sessionClock{}
+		options.clock = clock.Now
+	}
+	l, err := options.newLot(true)
+	if err != nil {
+		return c.fail(err)
+	}
+	var recorder *sessionRecorder
+	if *record != "" {
+		if recorder, err = newSessionRecorder(*record, clock); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	var code int
+	if flags.NArg() == 0 {
+		code = c.runInteractive(l, recorder)
+	} else if *keepGoing {
+		code = c.runKeepGoing(l, flags.Args())
+	} else {
+		code = c.runNonInteractive(l, flags.Args())
+	}
+	l.close()
+
+	// State file is left as loaded after the read-only commands.
+	if l.changed {
+		if err := options.saveState(l); err != nil && code == common.ExitCodeOK {
+			return c.fail(err)
+		}
+	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil && code == common.ExitCodeOK {
+			return c.fail(err)
+		}
+	}
+	return code
+}
+
+// replay executes the command logs against a fresh parking lot in the
+// keep-going mode. With --session, the inputs are recorded sessions and the
+// responses are verified against the recording. The resulting state is
+// saved if the state file is set.
+func (c *cli) replay(args []string) int {
+	flags := c.newFlagSet("replay", "replay [flags] input-file ...")
+	options := lotOptions{}
+	options.register(flags)
+	session := flags.Bool("session", false,
+		"Input files are sessions recorded with run --record. Time is replayed as recorded and differing responses are reported.")
+	if code, ok := c.parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return common.ExitCode(errIncorrectUsage)
+	}
+
+	var clock *sessionClock
+	if *session {
+		clock = &sessionClock{}
+		options.clock = clock.Now
+	}
+	l, err := options.newLot(false)
+	if err != nil {
+		return c.fail(err)
+	}
+	var code int
+	if *session {
+		code = c.replaySessions(l, clock, flags.Args())
+	} else {
+		code = c.runKeepGoing(l, flags.Args())
+	}
+	if err := options.saveState(l); err != nil && code == common.ExitCodeOK {
+		return c.fail(err)
+	}
+	return code
+}
+
+// runInteractive runs the program in the interactive mode. The session is
+// recorded if the recorder is set.
+func (c *cli) runInteractive(l *lot, recorder *sessionRecorder) int {
+	tokenizer := parser.NewTokenizer(c.stdin)
+	for {
+		fmt.Fprintf(c.stdout, "%s", "$ ") // Print prompt
+		out, err := l.process(&tokenizer)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Fprintf(c.stderr, "%s\n", err.Error())
+		}
+		fmt.Fprintf(c.stdout, "%s", out)
+		if recorder != nil {
+			if err := recorder.Record(&tokenizer, out, err); err != nil {
+				return c.fail(err)
+			}
+		}
+	}
+	return common.ExitCodeOK
+}
+
+// runNonInteractive runs the program in the non-interactive mode. In non-interactive mode,
+// any errors processing the input will terminate the program with the exit code of the
+// error category (See common.ExitCode).
+func (c *cli) runNonInteractive(l *lot, paths []string) int {
+	inputs, err := c.openInputs(paths)
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeInputs(inputs)
+
+	for _, in := range inputs {
+		tokenizer := parser.NewTokenizer(in.reader)
+		for {
+			out, err := l.process(&tokenizer)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return c.fail(err)
+			}
+			fmt.Fprint(c.stdout, out)
+		}
+	}
+	return common.ExitCodeOK
+}
+
+// runKeepGoing runs the program in the non-interactive mode that continues on errors.
+// Each failing line is reported to stderr along with its input name and line number.
+// A summary is printed to stderr at the end. Returns the exit code of the first failure
+// if any of the lines failed.
+func (c *cli) runKeepGoing(l *lot, paths []string) int {
+	inputs, err := c.openInputs(paths)
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeInputs(inputs)
+
+	summary := newBatchSummary()
+	for _, in := range inputs {
+		tokenizer := parser.NewTokenizer(in.reader)
+		for {
+			out, err := l.process(&tokenizer)
+			if err == io.EOF {
+				break
+			}
+			summary.Record(err)
+			if err != nil {
+				fmt.Fprintf(c.stderr, "%s:%d: %s\n", in.name, tokenizer.Line(), err.Error())
+				if common.CategoryOf(err) == common.CategoryInternal {
+					// Failure reading the input. Rest of the input can not be read.
+					break
+				}
+				continue
+			}
+			fmt.Fprint(c.stdout, out)
+		}
+	}
+	summary.Print(c.stderr)
+	return summary.ExitCode()
+}
+
+// process reads the next command from the tokenizer, executes it and
+// formats the result.
+func (l *lot) process(tokenizer *parser.Tokenizer) (string, error) {
+	command, err := parser.NextCommand(tokenizer)
+	if err != nil {
+		return "", err
+	}
+	result, err := l.executor.Execute(command)
+	l.changed = l.changed || processor.ChangesState(command.Type)
+	if err != nil {
+		return "", err
+	}
+	return l.formatter.Format(result), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"parking_lot/common"
+	"parking_lot/server"
+)
+
+// serve executes the commands posted over HTTP.
+func (c *cli) serve(args []string) int {
+	flags := c.newFlagSet("serve", "serve [flags]")
+	options := lotOptions{}
+	options.register(flags)
+	options.registerNotifications(flags, c.stderr)
+	addr := flags.String("addr", "127.0.0.1:8080", "Address to listen on. Listens on the loopback interface by default.")
+	if code, ok := c.parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return common.ExitCode(errIncorrectUsage)
+	}
+
+	l, err := options.newLot(true)
+	if err != nil {
+		return c.fail(err)
+	}
+	handler := server.NewHandler(&l.executor, l.formatter)
+	handler.OnChange = func() error {
+		return options.saveState(l)
+	}
+
+	fmt.Fprintf(c.stderr, "Listening on %s\n", *addr)
+	err = http.ListenAndServe(*addr, handler)
+	l.close()
+	return c.fail(err)
+}
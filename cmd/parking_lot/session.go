@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"parking_lot/common"
+	"parking_lot/parser"
+	"strings"
+	"time"
+)
+
+var (
+	errSessionInvalid = common.NewError("ERR_SESSION_INVALID", common.CategoryValidation,
+		"session recording is not valid")
+	errSessionDiverged = common.NewError("ERR_SESSION_DIVERGED", common.CategoryValidation,
+		"replayed responses differ from the session recording")
+)
+
+// sessionEntry is a command of the recorded session along with its
+// response. Recordings are stored as JSON lines, one entry per line.
+type sessionEntry struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Output  string    `json:"output,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// sessionClock is the clock of the executor while recording and replaying
+// the sessions. Time is frozen for the duration of a command so that the
+// recorded timestamp is the one all the time-dependent output is based on.
+type sessionClock struct {
+	now time.Time
+}
+
+// Now returns the time of the current command. The time is read from the
+// system clock on the first call after Reset.
+func (s *sessionClock) Now() time.Time {
+	if s.now.IsZero() {
+		s.now = time.Now()
+	}
+	return s.now
+}
+
+// Reset unfreezes the time before the next command is recorded.
+func (s *sessionClock) Reset() {
+	s.now = time.Time{}
+}
+
+// Set freezes the time of the next command at t.
+func (s *sessionClock) Set(t time.Time) {
+	s.now = t
+}
+
+// sessionRecorder writes the commands of the interactive session along
+// with their responses.
+type sessionRecorder struct {
+	clock   *sessionClock
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newSessionRecorder(path string, clock *sessionClock) (*sessionRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionRecorder{clock: clock, file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Record writes the command last read by the tokenizer along with its
+// response and resets the clock for the next command.
+func (r *sessionRecorder) Record(tokenizer *parser.Tokenizer, out string, err error) error {
+	entry := sessionEntry{Time: r.clock.Now(), Command: tokenizer.Text(), Output: out}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.clock.Reset()
+	return r.encoder.Encode(entry)
+}
+
+func (r *sessionRecorder) Close() error {
+	return r.file.Close()
+}
+
+// readSession reads the entries of the session recording.
+func readSession(in input) ([]sessionEntry, error) {
+	entries := make([]sessionEntry, 0)
+	scanner := bufio.NewScanner(in.reader)
+	scanner.Buffer(nil, 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry sessionEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errSessionInvalid.WithDetail("%s:%d: %s", in.name, line, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errInputNotReadable.WithDetail("%s: %s", in.name, err.Error())
+	}
+	return entries, nil
+}
+
+// replaySessions re-executes the recorded sessions against the parking lot
+// with the clock frozen at the recorded timestamps. Responses are printed
+// as in the interactive mode and every response differing from the
+// recording is reported to stderr.
+func (c *cli) replaySessions(l *lot, clock *sessionClock, paths []string) int {
+	inputs, err := c.openInputs(paths)
+	if err != nil {
+		return c.fail(err)
+	}
+	defer closeInputs(inputs)
+
+	replayed, diverged := 0, 0
+	for _, in := range inputs {
+		entries, err := readSession(in)
+		if err != nil {
+			return c.fail(err)
+		}
+		for i, entry := range entries {
+			clock.Set(entry.Time)
+			tokenizer := parser.NewTokenizer(strings.NewReader(entry.Command + "\n"))
+			out, err := l.process(&tokenizer)
+			got := sessionEntry{Time: entry.Time, Command: entry.Command, Output: out}
+			if err != nil {
+				got.Error = err.Error()
+				fmt.Fprintf(c.stderr, "%s\n", got.Error)
+			}
+			fmt.Fprint(c.stdout, out)
+
+			replayed++
+			if got != entry {
+				diverged++
+				fmt.Fprintf(c.stderr, "%s:%d: response to %q differs\n%s", in.name, i+1, entry.Command,
+					describeResponses(entry, got))
+			}
+		}
+	}
+	if diverged > 0 {
+		return c.fail(errSessionDiverged.WithDetail("%d of %d replayed responses differ from the recording", diverged, replayed))
+	}
+	return common.ExitCodeOK
+}
+
+// describeResponses renders the recorded and the replayed response of a
+// command for the divergence report.
+func describeResponses(recorded sessionEntry, replayed sessionEntry) string {
+	var b strings.Builder
+	describe := func(label string, entry sessionEntry) {
+		fmt.Fprintf(&b, "  %s:\n", label)
+		if entry.Output != "" {
+			for _, line := range strings.SplitAfter(strings.TrimSuffix(entry.Output, "\n"), "\n") {
+				fmt.Fprintf(&b, "    %s", line)
+			}
+			b.WriteString("\n")
+		}
+		if entry.Error != "" {
+			fmt.Fprintf(&b, "    error: %s\n", entry.Error)
+		}
+	}
+	describe("recorded", recorded)
+	describe("replayed", replayed)
+	return b.String()
+}
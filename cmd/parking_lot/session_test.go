@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplaySession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.jsonl")
+
+	input := "create_parking_lot 2\npark KA-01-HH-1234 White\n\nleave 2\nleave 1\nstats\n"
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"--record", path}, strings.NewReader(input), &stdout, &stderr); code != 0 {
+		t.Fatalf("run --record got exit code %d: %s", code, stderr.String())
+	}
+	entries, err := readSessionFile(path)
+	if err != nil {
+		t.Fatalf("readSession() got %v", err)
+	}
+	if len(entries) != 6 {
+		t.Fatalf("readSession() got %d entries want %d", len(entries), 6)
+	}
+	if entries[1].Command != "park KA-01-HH-1234 White" || entries[1].Output != "Allocated slot number: 1\n" {
+		t.Errorf("readSession() got %+v", entries[1])
+	}
+	if entries[3].Error == "" || entries[3].Output != "" {
+		t.Errorf("readSession() got %+v want an error", entries[3])
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runCLI([]string{"replay", "--session", path}, nil, &stdout, &stderr); code != 0 {
+		t.Errorf("replay --session got exit code %d: %s", code, stderr.String())
+	}
+	if strings.Contains(stderr.String(), "differs") {
+		t.Errorf("replay --session got %q", stderr.String())
+	}
+}
+
+func TestReplaySessionUsesRecordedTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.jsonl")
+
+	session := `{"time":"2020-01-01T09:00:00Z","command":"create_parking_lot 1","output":"Created a parking lot with 1 slots\n"}
+{"time":"2020-01-01T09:00:00Z","command":"park KA-01-HH-1234 White","output":"Allocated slot number: 1\n"}
+{"time":"2020-01-01T10:30:00Z","command":"leave 1","output":"Slot number 1 is free\n"}
+{"time":"2020-01-01T10:30:00Z","command":"stats","output":"Occupied slots: 0 of 1\n"}
+`
+	if err := ioutil.WriteFile(path, []byte(session), 0644); err != nil {
+		t.Fatalf("WriteFile() got %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"replay", "--session", path}, nil, &stdout, &stderr)
+	if code != 65 {
+		t.Errorf("replay --session got exit code %d want %d", code, 65)
+	}
+	if !strings.Contains(stdout.String(), "Average dwell time: 1h30m0s\n") {
+		t.Errorf("replay --session got output %q, want the dwell time of the recorded timestamps", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), `session.jsonl:4: response to "stats" differs`) ||
+		!strings.Contains(stderr.String(), "ERR_SESSION_DIVERGED: 1 of 4 replayed responses differ from the recording") {
+		t.Errorf("replay --session got stderr %q", stderr.String())
+	}
+}
+
+func TestRecordRequiresInteractiveMode(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"--record", "session.jsonl", "input.txt"}, nil, &stdout, &stderr); code != 64 {
+		t.Errorf("run --record got exit code %d want %d", code, 64)
+	}
+}
+
+func readSessionFile(path string) ([]sessionEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readSession(input{name: path, reader: file})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"parking_lot/common"
+	"sort"
+)
+
+// batchSummary counts the outcome of the commands processed in the
+// keep-going mode.
+type batchSummary struct {
+	run       int
+	succeeded int
+	failures  map[string]int // Error code - Count mapping
+	firstErr  error
+}
+
+func newBatchSummary() *batchSummary {
+	return &batchSummary{failures: make(map[string]int)}
+}
+
+// Record records the outcome of a single command.
+func (b *batchSummary) Record(err error) {
+	b.run++
+	if err == nil {
+		b.succeeded++
+		return
+	}
+	if b.firstErr == nil {
+		b.firstErr = err
+	}
+	b.failures[common.CodeOf(err)]++
+}
+
+// Print writes the summary to the writer.
+func (b *batchSummary) Print(w io.Writer) {
+	fmt.Fprintf(w, "Commands run: %d, succeeded: %d, failed: %d\n", b.run, b.succeeded, b.run-b.succeeded)
+	codes := make([]string, 0, len(b.failures))
+	for code := range b.failures {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %s: %d\n", code, b.failures[code])
+	}
+}
+
+// ExitCode returns the exit code of the first failure. Returns 0 if
+// all of the commands succeeded.
+func (b *batchSummary) ExitCode() int {
+	return common.ExitCode(b.firstErr)
+}
@@ -0,0 +1,98 @@
+package common
+
+import "math/bits"
+
+const wordSize = 64
+
+// Bitmap is a set of non-negative integers stored as bits. It grows up to
+// the largest member added. Bitmap keeps a cursor at the first word that may
+// have a member, which makes repeatedly taking the smallest member cheap, and
+// the number of members.
+type Bitmap struct {
+	words  []uint64
+	cursor int
+	count  int
+}
+
+// Add adds the member to the bitmap.
+func (b *Bitmap) Add(member int) {
+	word := member / wordSize
+	if word >= len(b.words) {
+		b.words = append(b.words, make([]uint64, word+1-len(b.words))...)
+	}
+	if bit := uint64(1) << uint(member%wordSize); b.words[word]&bit == 0 {
+		b.words[word] |= bit
+		b.count++
+	}
+	if word < b.cursor {
+		b.cursor = word
+	}
+}
+
+// AddRange adds the members in [from, to) to the bitmap.
+func (b *Bitmap) AddRange(from int, to int) {
+	if from >= to {
+		return
+	}
+	b.Add(to - 1)
+	for member := from; member < to; {
+		word := member / wordSize
+		if member%wordSize == 0 && member+wordSize <= to {
+			b.count += wordSize - bits.OnesCount64(b.words[word])
+			b.words[word] = ^uint64(0)
+			member += wordSize
+			continue
+		}
+		if bit := uint64(1) << uint(member%wordSize); b.words[word]&bit == 0 {
+			b.words[word] |= bit
+			b.count++
+		}
+		member++
+	}
+	if word := from / wordSize; word < b.cursor {
+		b.cursor = word
+	}
+}
+
+// Remove removes the member from the bitmap.
+func (b *Bitmap) Remove(member int) {
+	word := member / wordSize
+	if bit := uint64(1) << uint(member%wordSize); word < len(b.words) && b.words[word]&bit != 0 {
+		b.words[word] &^= bit
+		b.count--
+	}
+}
+
+// Contains tells whether the member is in the bitmap.
+func (b *Bitmap) Contains(member int) bool {
+	word := member / wordSize
+	return word < len(b.words) && b.words[word]&(1<<uint(member%wordSize)) != 0
+}
+
+// Min returns the smallest member. Returns -1 if the bitmap is empty.
+func (b *Bitmap) Min() int {
+	for b.cursor < len(b.words) && b.words[b.cursor] == 0 {
+		b.cursor++
+	}
+	if b.cursor >= len(b.words) {
+		return -1
+	}
+	return b.cursor*wordSize + bits.TrailingZeros64(b.words[b.cursor])
+}
+
+// Len returns the number of members.
+func (b *Bitmap) Len() int {
+	return b.count
+}
+
+// Members returns the members in ascending order.
+func (b *Bitmap) Members() []int {
+	members := make([]int, 0, b.Len())
+	for i, word := range b.words[b.cursor:] {
+		for word != 0 {
+			members = append(members, (b.cursor+i)*wordSize+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return members
+}
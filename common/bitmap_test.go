@@ -0,0 +1,48 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBitmap_Min(t *testing.T) {
+	b := Bitmap{}
+	if min := b.Min(); min != -1 {
+		t.Errorf("Min() got %d want %d", min, -1)
+	}
+	b.AddRange(3, 200)
+	b.Remove(3)
+	b.Remove(4)
+	if min := b.Min(); min != 5 {
+		t.Errorf("Min() got %d want %d", min, 5)
+	}
+	for i := 5; i < 130; i++ {
+		b.Remove(i)
+	}
+	if min := b.Min(); min != 130 {
+		t.Errorf("Min() got %d want %d", min, 130)
+	}
+	// Cursor moves back for the members added before it.
+	b.Add(64)
+	if min := b.Min(); min != 64 {
+		t.Errorf("Min() got %d want %d", min, 64)
+	}
+	if b.Len() != 71 {
+		t.Errorf("Len() got %d want %d", b.Len(), 71)
+	}
+}
+
+func TestBitmap_Members(t *testing.T) {
+	b := Bitmap{}
+	for _, member := range []int{130, 1, 63, 64} {
+		b.Add(member)
+	}
+	b.Remove(63)
+	expected := []int{1, 64, 130}
+	if members := b.Members(); !reflect.DeepEqual(members, expected) {
+		t.Errorf("Members() got %v want %v", members, expected)
+	}
+	if !b.Contains(64) || b.Contains(63) || b.Contains(1000) {
+		t.Errorf("Contains() got unexpected membership")
+	}
+}
@@ -0,0 +1,142 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Category classifies errors by the kind of failure. Each category maps to
+// a distinct process exit code in the non-interactive mode.
+type Category int
+
+const (
+	// CategoryInternal specifies unexpected failures such as I/O errors.
+	CategoryInternal Category = iota
+	// CategoryUsage specifies malformed or unknown commands and incorrect CLI usage.
+	CategoryUsage
+	// CategoryValidation specifies well-formed commands with invalid arguments or state.
+	CategoryValidation
+	// CategoryCapacity specifies commands that can not be served due to lack of space.
+	CategoryCapacity
+	// CategoryNotFound specifies commands referring to slots or cars that do not exist.
+	CategoryNotFound
+)
+
+// Process exit codes for each of the error category. Values follow sysexits.h.
+const (
+	ExitCodeOK         = 0
+	ExitCodeUsage      = 64
+	ExitCodeValidation = 65
+	ExitCodeNotFound   = 66
+	ExitCodeCapacity   = 69
+	ExitCodeInternal   = 70
+)
+
+func (c Category) String() string {
+	switch c {
+	case CategoryUsage:
+		return "usage"
+	case CategoryValidation:
+		return "validation"
+	case CategoryCapacity:
+		return "capacity"
+	case CategoryNotFound:
+		return "not-found"
+	default:
+		return "internal"
+	}
+}
+
+// ExitCode returns the process exit code of the category.
+func (c Category) ExitCode() int {
+	switch c {
+	case CategoryUsage:
+		return ExitCodeUsage
+	case CategoryValidation:
+		return ExitCodeValidation
+	case CategoryCapacity:
+		return ExitCodeCapacity
+	case CategoryNotFound:
+		return ExitCodeNotFound
+	default:
+		return ExitCodeInternal
+	}
+}
+
+// Error is the error type returned by the parking lot packages. Code is
+// stable and meant to be matched by the callers, Detail is meant for humans.
+type Error struct {
+	Code     string
+	Category Category
+	Detail   string
+}
+
+// NewError builds and returns the Error.
+func NewError(code string, category Category, detail string) *Error {
+	return &Error{
+		Code:     code,
+		Category: category,
+		Detail:   detail,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+// WithDetail returns a copy of the error with the detail replaced.
+// The copy matches the original with errors.Is.
+func (e *Error) WithDetail(format string, args ...interface{}) *Error {
+	return &Error{
+		Code:     e.Code,
+		Category: e.Category,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+// Is reports whether the target is an Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// CategoryOf returns the category of the error. Errors which are not of
+// type Error are considered internal.
+func CategoryOf(err error) Category {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	return CategoryInternal
+}
+
+// CodeOf returns the code of the error. Errors which are not of type Error
+// are reported with the code ERR_INTERNAL.
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return "ERR_INTERNAL"
+}
+
+// DetailOf returns the human-readable detail of the error. Errors which are
+// not of type Error are described by their message.
+func DetailOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Detail
+	}
+	return err.Error()
+}
+
+// ExitCode returns the process exit code for the error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+	return CategoryOf(err).ExitCode()
+}
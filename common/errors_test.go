@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_Error(t *testing.T) {
+	err := NewError("ERR_TEST", CategoryValidation, "something is invalid")
+	expected := "ERR_TEST: something is invalid"
+	if err.Error() != expected {
+		t.Errorf("Error() got %q want %q", err.Error(), expected)
+	}
+}
+
+func TestError_WithDetailMatchesOriginal(t *testing.T) {
+	err := NewError("ERR_TEST", CategoryValidation, "something is invalid")
+	detailed := err.WithDetail("slot %d is invalid", 4)
+	if !errors.Is(detailed, err) {
+		t.Errorf("errors.Is() got false want true")
+	}
+	if detailed.Error() != "ERR_TEST: slot 4 is invalid" {
+		t.Errorf("WithDetail() got %q", detailed.Error())
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: ExitCodeOK},
+		{name: "usage", err: NewError("ERR_A", CategoryUsage, ""), want: ExitCodeUsage},
+		{name: "validation", err: NewError("ERR_B", CategoryValidation, ""), want: ExitCodeValidation},
+		{name: "capacity", err: NewError("ERR_C", CategoryCapacity, ""), want: ExitCodeCapacity},
+		{name: "not found", err: NewError("ERR_D", CategoryNotFound, ""), want: ExitCodeNotFound},
+		{name: "wrapped", err: fmt.Errorf("line 4: %w", NewError("ERR_D", CategoryNotFound, "")), want: ExitCodeNotFound},
+		{name: "foreign", err: errors.New("boom"), want: ExitCodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() got %d want %d", got, tt.want)
+			}
+		})
+	}
+}
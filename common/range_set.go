@@ -0,0 +1,137 @@
+package common
+
+import "sort"
+
+// Range is the range of integers from From up to, but not including, To.
+type Range struct {
+	From int
+	To   int
+}
+
+// RangeSet is a set of integers stored as the sorted disjoint ranges of
+// consecutive members. Memory grows with the number of ranges rather than
+// members, which keeps the mostly contiguous sets (Eg: free slots of a large
+// parking lot) small.
+type RangeSet struct {
+	ranges []Range
+	count  int
+}
+
+// find returns the index of the first range ending after the member.
+func (s *RangeSet) find(member int) int {
+	return sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].To > member })
+}
+
+// Add adds the member to the set.
+func (s *RangeSet) Add(member int) {
+	i := s.find(member)
+	if i < len(s.ranges) && s.ranges[i].From <= member {
+		return
+	}
+	s.count++
+	joinsPrevious := i > 0 && s.ranges[i-1].To == member
+	joinsNext := i < len(s.ranges) && s.ranges[i].From == member+1
+	switch {
+	case joinsPrevious && joinsNext:
+		s.ranges[i-1].To = s.ranges[i].To
+		s.delete(i)
+	case joinsPrevious:
+		s.ranges[i-1].To++
+	case joinsNext:
+		s.ranges[i].From--
+	default:
+		s.ranges = append(s.ranges, Range{})
+		copy(s.ranges[i+1:], s.ranges[i:])
+		s.ranges[i] = Range{From: member, To: member + 1}
+	}
+}
+
+// AddRange adds the members in [from, to) to the set.
+func (s *RangeSet) AddRange(from int, to int) {
+	if from >= to {
+		return
+	}
+	// Ranges overlapping or adjacent to the new one are merged into it.
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].To >= from })
+	j := i
+	for ; j < len(s.ranges) && s.ranges[j].From <= to; j++ {
+		if s.ranges[j].From < from {
+			from = s.ranges[j].From
+		}
+		if s.ranges[j].To > to {
+			to = s.ranges[j].To
+		}
+		s.count -= s.ranges[j].To - s.ranges[j].From
+	}
+	s.count += to - from
+	merged := append([]Range{{From: from, To: to}}, s.ranges[j:]...)
+	s.ranges = append(s.ranges[:i], merged...)
+}
+
+// Remove removes the member from the set.
+func (s *RangeSet) Remove(member int) {
+	i := s.find(member)
+	if i == len(s.ranges) || s.ranges[i].From > member {
+		return
+	}
+	s.count--
+	r := s.ranges[i]
+	switch {
+	case r.From == member && r.To == member+1:
+		s.delete(i)
+	case r.From == member:
+		s.ranges[i].From++
+	case r.To == member+1:
+		s.ranges[i].To--
+	default:
+		s.ranges[i].To = member
+		s.ranges = append(s.ranges, Range{})
+		copy(s.ranges[i+2:], s.ranges[i+1:])
+		s.ranges[i+1] = Range{From: member + 1, To: r.To}
+	}
+}
+
+// delete drops the range at the index. Dropping the first range, as taking
+// the smallest members does, moves none of the others.
+func (s *RangeSet) delete(i int) {
+	if i == 0 {
+		s.ranges = s.ranges[1:]
+		return
+	}
+	s.ranges = append(s.ranges[:i], s.ranges[i+1:]...)
+}
+
+// Contains tells whether the member is in the set.
+func (s *RangeSet) Contains(member int) bool {
+	i := s.find(member)
+	return i < len(s.ranges) && s.ranges[i].From <= member
+}
+
+// Min returns the smallest member. Returns -1 if the set is empty.
+func (s *RangeSet) Min() int {
+	if len(s.ranges) == 0 {
+		return -1
+	}
+	return s.ranges[0].From
+}
+
+// Len returns the number of members.
+func (s *RangeSet) Len() int {
+	return s.count
+}
+
+// Ranges returns the number of the ranges the members are stored in.
+func (s *RangeSet) Ranges() int {
+	return len(s.ranges)
+}
+
+// Members returns the members in ascending order.
+func (s *RangeSet) Members() []int {
+	members := make([]int, 0, s.count)
+	for _, r := range s.ranges {
+		for member := r.From; member < r.To; member++ {
+			members = append(members, member)
+		}
+	}
+	return members
+}
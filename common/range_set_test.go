@@ -0,0 +1,53 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRangeSet_AddRemove(t *testing.T) {
+	s := RangeSet{}
+	if min := s.Min(); min != -1 {
+		t.Errorf("Min() got %d want %d", min, -1)
+	}
+	s.AddRange(1, 11)
+	s.Remove(1)
+	s.Remove(5)
+	s.Remove(10)
+	s.Remove(42)
+	if min := s.Min(); min != 2 {
+		t.Errorf("Min() got %d want %d", min, 2)
+	}
+	expected := []int{2, 3, 4, 6, 7, 8, 9}
+	if members := s.Members(); !reflect.DeepEqual(members, expected) || s.Len() != len(expected) || s.Ranges() != 2 {
+		t.Errorf("Members() got %v in %d ranges want %v in 2", members, s.Ranges(), expected)
+	}
+
+	// Adding the member between two ranges joins them.
+	s.Add(5)
+	s.Add(5)
+	s.Add(12)
+	if s.Ranges() != 2 || s.Len() != 9 || !s.Contains(5) || s.Contains(10) || s.Contains(11) {
+		t.Errorf("Add() got %v in %d ranges", s.Members(), s.Ranges())
+	}
+	s.AddRange(0, 20)
+	if s.Ranges() != 1 || s.Len() != 20 || s.Min() != 0 {
+		t.Errorf("AddRange() got %v in %d ranges", s.Members(), s.Ranges())
+	}
+}
+
+func TestRangeSet_TakeSmallest(t *testing.T) {
+	s := RangeSet{}
+	s.AddRange(1, 4)
+	s.Add(6)
+	for _, want := range []int{1, 2, 3, 6, -1} {
+		min := s.Min()
+		if min != want {
+			t.Errorf("Min() got %d want %d", min, want)
+		}
+		s.Remove(min)
+	}
+	if s.Len() != 0 || s.Ranges() != 0 {
+		t.Errorf("Len() got %d in %d ranges want empty", s.Len(), s.Ranges())
+	}
+}
@@ -1,13 +1,12 @@
 package common
 
 import (
-	"errors"
 	"parking_lot/common/unsafelinkedlist"
 )
 
 var (
-	ErrSetMemberExists    = errors.New("ERR_SET_MEMBER_EXISTS")
-	ErrSetMemberNotExists = errors.New("ERR_SET_MEMBER_NOT_EXISTS")
+	ErrSetMemberExists    = NewError("ERR_SET_MEMBER_EXISTS", CategoryInternal, "member already exists in the set")
+	ErrSetMemberNotExists = NewError("ERR_SET_MEMBER_NOT_EXISTS", CategoryNotFound, "member does not exist in the set")
 )
 
 // LinkedHashIntSet is a set data-structure for int data type.
@@ -45,3 +44,14 @@ func (s *LinkedHashIntSet) Remove(member int) error {
 func (s *LinkedHashIntSet) Members() []int {
 	return s.list.Values()
 }
+
+// Len returns the number of members in the set.
+func (s *LinkedHashIntSet) Len() int {
+	return s.list.Len()
+}
+
+// Each calls fn with the members in the insert order. Unlike Members, it
+// does not allocate.
+func (s *LinkedHashIntSet) Each(fn func(member int)) {
+	s.list.Each(fn)
+}
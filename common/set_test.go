@@ -58,3 +58,54 @@ func TestLinkedHashIntSet_Members(t *testing.T) {
 		t.Errorf("Members() got %+v want %+v", actual, expected)
 	}
 }
+
+func TestLinkedHashIntSet_Each(t *testing.T) {
+	set := NewLinkedHashIntSet()
+	_ = set.Add(20)
+	_ = set.Add(10)
+	members := make([]int, 0, set.Len())
+	set.Each(func(member int) {
+		members = append(members, member)
+	})
+	if !reflect.DeepEqual(members, []int{20, 10}) {
+		t.Errorf("Each() got %v want %v", members, []int{20, 10})
+	}
+}
+
+func BenchmarkLinkedHashIntSet_AddRemove(b *testing.B) {
+	set := NewLinkedHashIntSet()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = set.Add(i)
+		if i >= 64 {
+			_ = set.Remove(i - 64)
+		}
+	}
+}
+
+func BenchmarkLinkedHashIntSet_Members(b *testing.B) {
+	set := NewLinkedHashIntSet()
+	for i := 0; i < 64; i++ {
+		_ = set.Add(i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = set.Members()
+	}
+}
+
+func BenchmarkLinkedHashIntSet_Each(b *testing.B) {
+	set := NewLinkedHashIntSet()
+	for i := 0; i < 64; i++ {
+		_ = set.Add(i)
+	}
+	sum := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Each(func(member int) {
+			sum += member
+		})
+	}
+}
@@ -73,3 +73,15 @@ func (list *IntDoublyLinkedList) Values() []int {
 	}
 	return values
 }
+
+// Len returns the number of elements in the linked list.
+func (list *IntDoublyLinkedList) Len() int {
+	return list.size
+}
+
+// Each calls fn with the element values in the linked list order.
+func (list *IntDoublyLinkedList) Each(fn func(value int)) {
+	for item := list.first; item != nil; item = item.next {
+		fn(item.value)
+	}
+}
@@ -0,0 +1,157 @@
+package dao
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// globMeta lists the characters with a special meaning in the registration
+// number patterns.
+const globMeta = "*?[\\"
+
+// Filter selects the parked cars. Zero valued fields match any car and the
+// set fields are combined with AND.
+type Filter struct {
+	Color string
+	// RegNum is the prefix of the registration number, or a glob pattern
+	// (Eg: "KA-01-*") if it contains any of the "*?[" characters.
+	RegNum string
+	// FromSlot and ToSlot bound the slot numbers, inclusive.
+	FromSlot int
+	ToSlot   int
+	Permit   SlotCategory
+	// ParkedAfter and ParkedBefore bound the time the car was parked at, exclusive.
+	ParkedAfter  time.Time
+	ParkedBefore time.Time
+}
+
+// Matches tells whether the car parked at the slot matches the filter.
+func (f *Filter) Matches(slotID int, car *Car) bool {
+	if f.Color != "" && car.Color != f.Color {
+		return false
+	}
+	if f.RegNum != "" && !matchRegNum(f.RegNum, car.RegistrationNumber) {
+		return false
+	}
+	if (f.FromSlot > 0 && slotID < f.FromSlot) || (f.ToSlot > 0 && slotID > f.ToSlot) {
+		return false
+	}
+	if f.Permit != "" && car.Permit != f.Permit {
+		return false
+	}
+	if !f.ParkedAfter.IsZero() && !car.ParkedAt.After(f.ParkedAfter) {
+		return false
+	}
+	if !f.ParkedBefore.IsZero() && !car.ParkedAt.Before(f.ParkedBefore) {
+		return false
+	}
+	return true
+}
+
+// matchRegNum matches the registration number against the prefix or glob
+// pattern.
+func matchRegNum(pattern string, regNum string) bool {
+	if !strings.ContainsAny(pattern, globMeta) {
+		return strings.HasPrefix(regNum, pattern)
+	}
+	matched, err := path.Match(pattern, regNum)
+	return err == nil && matched
+}
+
+// literalPrefix returns the part of the pattern preceding the first glob
+// character. Every registration number matching the pattern starts with it.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, globMeta); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// sortedIndexChunk is the number of keys a chunk of the sortedIndex is
+// split at. Adding or removing a key moves at most that many keys.
+const sortedIndexChunk = 1024
+
+// sortedIndex keeps the keys sorted to look them up by the prefix. Keys are
+// kept in the sorted chunks so that adding and removing the keys stays cheap
+// for the large number of keys.
+type sortedIndex struct {
+	chunks [][]string
+	size   int
+}
+
+// chunk returns the index of the chunk the key belongs to.
+func (i *sortedIndex) chunk(key string) int {
+	c := sort.Search(len(i.chunks), func(c int) bool { return i.chunks[c][0] > key }) - 1
+	if c < 0 {
+		return 0
+	}
+	return c
+}
+
+func (i *sortedIndex) Add(key string) {
+	if len(i.chunks) == 0 {
+		i.chunks = append(i.chunks, []string{key})
+		i.size++
+		return
+	}
+	c := i.chunk(key)
+	keys := i.chunks[c]
+	position := sort.SearchStrings(keys, key)
+	if position < len(keys) && keys[position] == key {
+		return
+	}
+	keys = append(keys, "")
+	copy(keys[position+1:], keys[position:])
+	keys[position] = key
+	i.chunks[c] = keys
+	i.size++
+
+	if len(keys) >= 2*sortedIndexChunk {
+		// Split the chunk in halves.
+		tail := make([]string, len(keys)-sortedIndexChunk, 2*sortedIndexChunk)
+		copy(tail, keys[sortedIndexChunk:])
+		i.chunks[c] = keys[:sortedIndexChunk:sortedIndexChunk]
+		i.chunks = append(i.chunks, nil)
+		copy(i.chunks[c+2:], i.chunks[c+1:])
+		i.chunks[c+1] = tail
+	}
+}
+
+func (i *sortedIndex) Remove(key string) {
+	if len(i.chunks) == 0 {
+		return
+	}
+	c := i.chunk(key)
+	keys := i.chunks[c]
+	position := sort.SearchStrings(keys, key)
+	if position >= len(keys) || keys[position] != key {
+		return
+	}
+	i.chunks[c] = append(keys[:position], keys[position+1:]...)
+	i.size--
+	if len(i.chunks[c]) == 0 {
+		i.chunks = append(i.chunks[:c], i.chunks[c+1:]...)
+	}
+}
+
+// Len returns the number of keys.
+func (i *sortedIndex) Len() int {
+	return i.size
+}
+
+// WithPrefix returns the keys starting with the prefix in ascending order.
+func (i *sortedIndex) WithPrefix(prefix string) []string {
+	result := make([]string, 0)
+	for c := i.chunk(prefix); c < len(i.chunks); c++ {
+		keys := i.chunks[c]
+		for position := sort.SearchStrings(keys, prefix); position < len(keys); position++ {
+			if !strings.HasPrefix(keys[position], prefix) {
+				return result
+			}
+			result = append(result, keys[position])
+		}
+	}
+	return result
+}
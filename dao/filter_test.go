@@ -0,0 +1,64 @@
+package dao
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSortedIndex_WithPrefix(t *testing.T) {
+	index := sortedIndex{}
+	expected := make(map[string]bool)
+	random := rand.New(rand.NewSource(1))
+	for _, i := range random.Perm(5 * sortedIndexChunk) {
+		key := fmt.Sprintf("KA-%02d-HH-%04d", i%20, i)
+		index.Add(key)
+		expected[key] = true
+	}
+	for _, i := range random.Perm(5 * sortedIndexChunk)[:2*sortedIndexChunk] {
+		key := fmt.Sprintf("KA-%02d-HH-%04d", i%20, i)
+		index.Remove(key)
+		delete(expected, key)
+	}
+
+	if index.Len() != len(expected) {
+		t.Errorf("Len() got %d want %d", index.Len(), len(expected))
+	}
+	for _, prefix := range []string{"", "KA-07", "KA-19-HH-5", "KB"} {
+		want := make([]string, 0)
+		for key := range expected {
+			if strings.HasPrefix(key, prefix) {
+				want = append(want, key)
+			}
+		}
+		sort.Strings(want)
+		if got := index.WithPrefix(prefix); !reflect.DeepEqual(got, want) {
+			t.Errorf("WithPrefix(%q) got %d keys want %d", prefix, len(got), len(want))
+		}
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	car := Car{RegistrationNumber: "KA-01-HH-1234", Color: "White"}
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{name: "empty", filter: Filter{}, want: true},
+		{name: "prefix", filter: Filter{RegNum: "KA-01-HH"}, want: true},
+		{name: "glob", filter: Filter{RegNum: "KA-*-HH-12??"}, want: true},
+		{name: "glob mismatch", filter: Filter{RegNum: "KA-02-*"}, want: false},
+		{name: "colour and slots", filter: Filter{Color: "White", FromSlot: 5, ToSlot: 6}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(4, &car); got != tt.want {
+				t.Errorf("Matches() got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package dao
 
 import (
 	"parking_lot/common"
+	"sort"
 )
 
 // index is a helper struct to index specific attributes
-// such as colors.
+// such as colors. Keys are removed once they have no members, so the
+// memory used grows with the number of parked cars only.
 type index struct {
 	idx map[string]*common.LinkedHashIntSet
 }
@@ -17,18 +19,26 @@ func newIndex() index {
 }
 
 func (i *index) Add(slotID int, key string) error {
-	set := common.NewLinkedHashIntSet()
-	if _, ok := i.idx[key]; !ok {
+	set, ok := i.idx[key]
+	if !ok {
+		set = common.NewLinkedHashIntSet()
 		i.idx[key] = set
 	}
-	return i.idx[key].Add(slotID)
+	return set.Add(slotID)
 }
 
 func (i *index) Remove(slotID int, key string) error {
-	if _, ok := i.idx[key]; ok {
-		return i.idx[key].Remove(slotID)
+	set, ok := i.idx[key]
+	if !ok {
+		return common.ErrSetMemberNotExists
 	}
-	return common.ErrSetMemberNotExists
+	if err := set.Remove(slotID); err != nil {
+		return err
+	}
+	if set.Len() == 0 {
+		delete(i.idx, key)
+	}
+	return nil
 }
 
 func (i *index) Exists(key string) bool {
@@ -36,79 +46,189 @@ func (i *index) Exists(key string) bool {
 	return ok
 }
 
+// Len returns the number of members of the key.
+func (i *index) Len(key string) int {
+	if set, ok := i.idx[key]; ok {
+		return set.Len()
+	}
+	return 0
+}
+
+// Each calls fn with the members of the key in the insert order.
+func (i *index) Each(key string, fn func(slotID int)) {
+	if set, ok := i.idx[key]; ok {
+		set.Each(fn)
+	}
+}
+
 func (i *index) Membership(key string) []int {
-	if _, ok := i.idx[key]; !ok {
-		i.idx[key] = common.NewLinkedHashIntSet()
+	set, ok := i.idx[key]
+	if !ok {
 		return []int{}
 	}
-	result := make([]int, 0)
-	for _, k := range i.idx[key].Members() {
-		result = append(result, k)
-	}
-	return result
+	return set.Members()
 }
 
+// InMemoryStorage stores the parking lot in memory. Only the slots that are
+// occupied, reserved or closed are stored, the rest are implied by the size.
 type InMemoryStorage struct {
 	size          int
-	slots         []Slot
-	slotsByColor  index // Color-SlotID mapping
-	slotsByRegNum index // Registration number - SlotID mapping
+	slots         map[int]*Slot // SlotID - Slot mapping of the slots differing from the free general slot
+	slotsByColor  index         // Color-SlotID mapping
+	slotsByRegNum index         // Registration number - SlotID mapping
+	regNums       sortedIndex
 }
 
 func (ims *InMemoryStorage) SetSize(size int) {
-	ims.slots = make([]Slot, size, size)
-	for i := 0; i < size; i++ {
-		ims.slots[i] = Slot{
-			ID:  i + 1,
-			Car: nil,
-		}
-	}
+	ims.slots = make(map[int]*Slot)
 	ims.size = size
 	ims.slotsByColor = newIndex()
 	ims.slotsByRegNum = newIndex()
+	ims.regNums = sortedIndex{}
+}
+
+// slot returns the stored slot, or nil if it is a free general slot.
+func (ims *InMemoryStorage) slot(slotID int) *Slot {
+	return ims.slots[slotID]
+}
+
+// mutableSlot returns the stored slot, storing it first if required.
+func (ims *InMemoryStorage) mutableSlot(slotID int) *Slot {
+	slot, ok := ims.slots[slotID]
+	if !ok {
+		slot = &Slot{ID: slotID, Category: SlotCategoryGeneral}
+		ims.slots[slotID] = slot
+	}
+	return slot
+}
+
+// release stops storing the slot once it is a free general slot again.
+func (ims *InMemoryStorage) release(slot *Slot) {
+	if slot.Car == nil && slot.Category == SlotCategoryGeneral && !slot.Closed {
+		delete(ims.slots, slot.ID)
+	}
 }
 
 func (ims *InMemoryStorage) Park(slotID int, car *Car) error {
-	if slotID > ims.size {
+	if slotID <= 0 || slotID > ims.size {
 		return ErrSlotExceedsAvailableParking
 	}
 
-	if ims.slots[slotID-1].Car != nil {
+	if slot := ims.slot(slotID); slot != nil && slot.Car != nil {
 		return ErrSlotAlreadyOccupied
+	} else if slot != nil && slot.Closed {
+		return ErrSlotClosed
 	}
 
-	if ims.slotsByRegNum.Exists(car.RegistrationNumber) && len(ims.slotsByRegNum.Membership(car.RegistrationNumber)) > 0 {
+	if ims.slotsByRegNum.Len(car.RegistrationNumber) > 0 {
 		return ErrDuplicateRegNum
 	}
 
-	ims.slots[slotID-1].Car = car
+	ims.mutableSlot(slotID).Car = car
 	ims.slotsByColor.Add(slotID, car.Color)
 	ims.slotsByRegNum.Add(slotID, car.RegistrationNumber)
+	ims.regNums.Add(car.RegistrationNumber)
 	return nil
 }
 
 func (ims *InMemoryStorage) Leave(slotID int) (*Car, error) {
-	if slotID > ims.size {
+	if slotID <= 0 || slotID > ims.size {
 		return nil, ErrSlotExceedsAvailableParking
 	}
 
-	car := ims.slots[slotID-1].Car
-	if car == nil {
+	slot := ims.slot(slotID)
+	if slot == nil || slot.Car == nil {
 		return nil, ErrSlotNotOccupied
 	}
 
-	ims.slots[slotID-1].Car = nil
+	car := slot.Car
+	slot.Car = nil
+	ims.release(slot)
 	ims.slotsByColor.Remove(slotID, car.Color)
 	ims.slotsByRegNum.Remove(slotID, car.RegistrationNumber)
+	ims.regNums.Remove(car.RegistrationNumber)
 	return car, nil
 }
 
-func (ims *InMemoryStorage) RegNumForCarsWithColor(color string) []string {
-	slotIDs := ims.slotsByColor.Membership(color)
-	regNums := make([]string, 0)
-	for _, id := range slotIDs {
-		regNums = append(regNums, ims.slots[id-1].Car.RegistrationNumber)
+func (ims *InMemoryStorage) SetCategory(slotID int, category SlotCategory) error {
+	if slotID <= 0 || slotID > ims.size {
+		return ErrSlotExceedsAvailableParking
+	}
+	slot := ims.mutableSlot(slotID)
+	slot.Category = category
+	ims.release(slot)
+	return nil
+}
+
+func (ims *InMemoryStorage) Close(slotID int, reason string) error {
+	if slotID <= 0 || slotID > ims.size {
+		return ErrSlotExceedsAvailableParking
+	}
+	if slot := ims.slot(slotID); slot != nil && slot.Car != nil {
+		return ErrSlotAlreadyOccupied
+	} else if slot != nil && slot.Closed {
+		return ErrSlotClosed
+	}
+	slot := ims.mutableSlot(slotID)
+	slot.Closed = true
+	slot.ClosedReason = reason
+	return nil
+}
+
+func (ims *InMemoryStorage) Open(slotID int) error {
+	if slotID <= 0 || slotID > ims.size {
+		return ErrSlotExceedsAvailableParking
+	}
+	slot := ims.slot(slotID)
+	if slot == nil || !slot.Closed {
+		return ErrSlotNotClosed
+	}
+	slot.Closed = false
+	slot.ClosedReason = ""
+	ims.release(slot)
+	return nil
+}
+
+func (ims *InMemoryStorage) Move(fromSlotID int, toSlotID int) error {
+	if fromSlotID <= 0 || fromSlotID > ims.size || toSlotID <= 0 || toSlotID > ims.size {
+		return ErrSlotExceedsAvailableParking
 	}
+	from := ims.slot(fromSlotID)
+	if from == nil || from.Car == nil {
+		return ErrSlotNotOccupied
+	}
+	if to := ims.slot(toSlotID); to != nil && to.Car != nil {
+		return ErrSlotAlreadyOccupied
+	} else if to != nil && to.Closed {
+		return ErrSlotClosed
+	}
+
+	car := from.Car
+	from.Car = nil
+	ims.release(from)
+	ims.mutableSlot(toSlotID).Car = car
+	ims.slotsByColor.Remove(fromSlotID, car.Color)
+	ims.slotsByColor.Add(toSlotID, car.Color)
+	ims.slotsByRegNum.Remove(fromSlotID, car.RegistrationNumber)
+	ims.slotsByRegNum.Add(toSlotID, car.RegistrationNumber)
+	return nil
+}
+
+func (ims *InMemoryStorage) Slot(slotID int) (Slot, error) {
+	if slotID <= 0 || slotID > ims.size {
+		return Slot{}, ErrSlotExceedsAvailableParking
+	}
+	if slot := ims.slot(slotID); slot != nil {
+		return *slot, nil
+	}
+	return Slot{ID: slotID, Category: SlotCategoryGeneral}, nil
+}
+
+func (ims *InMemoryStorage) RegNumForCarsWithColor(color string) []string {
+	regNums := make([]string, 0, ims.slotsByColor.Len(color))
+	ims.slotsByColor.Each(color, func(slotID int) {
+		regNums = append(regNums, ims.slots[slotID].Car.RegistrationNumber)
+	})
 	return regNums
 }
 
@@ -117,31 +237,117 @@ func (ims *InMemoryStorage) SlotNumForCarsWithColor(color string) []int {
 }
 
 func (ims *InMemoryStorage) SlotNumForCarWithRegNum(regNum string) int {
-	slotNums := ims.slotsByRegNum.Membership(regNum)
-	if len(slotNums) <= 0 {
-		return 0
-	}
-	return slotNums[0]
-}
-
-func (ims *InMemoryStorage) Status() []Status {
-	result := make([]Status, 0, ims.size)
-	for i := 0; i < ims.size; i++ {
-		slot := ims.slots[i]
-		car := slot.Car
-		if car != nil {
-			result = append(result, Status{
-				SlotNum: slot.ID,
-				RegNum:  car.RegistrationNumber,
-				Color:   car.Color,
-			})
-		} else {
-			result = append(result, Status{
-				SlotNum: slot.ID,
-				RegNum:  "",
-				Color:   "",
-			})
+	slotNum := 0
+	ims.slotsByRegNum.Each(regNum, func(slotID int) {
+		slotNum = slotID
+	})
+	return slotNum
+}
+
+// Slots returns the status of the occupied, closed and reserved slots ordered
+// by the slot number. Takes time and memory in the number of such slots only.
+func (ims *InMemoryStorage) Slots() []Status {
+	slotIDs := make([]int, 0, len(ims.slots))
+	for slotID := range ims.slots {
+		slotIDs = append(slotIDs, slotID)
+	}
+	sort.Ints(slotIDs)
+	result := make([]Status, 0, len(slotIDs))
+	for _, slotID := range slotIDs {
+		result = append(result, ims.slots[slotID].status())
+	}
+	return result
+}
+
+// status returns the status of the slot.
+func (slot *Slot) status() Status {
+	if car := slot.Car; car != nil {
+		return Status{
+			SlotNum:  slot.ID,
+			RegNum:   car.RegistrationNumber,
+			Color:    car.Color,
+			Category: slot.Category,
+			Permit:   car.Permit,
+			ParkedAt: car.ParkedAt,
+		}
+	}
+	return Status{
+		SlotNum:      slot.ID,
+		RegNum:       "",
+		Color:        "",
+		Category:     slot.Category,
+		Closed:       slot.Closed,
+		ClosedReason: slot.ClosedReason,
+	}
+}
+
+// Find returns the parked cars matching the filter, ordered by the slot
+// number. Candidates are looked up in the index narrowing them down the most.
+func (ims *InMemoryStorage) Find(filter Filter) []Status {
+	candidates := ims.findCandidates(&filter)
+	sort.Ints(candidates)
+	result := make([]Status, 0)
+	for _, slotID := range candidates {
+		slot := ims.slot(slotID)
+		if slot == nil || slot.Car == nil || !filter.Matches(slotID, slot.Car) {
+			continue
 		}
+		result = append(result, Status{
+			SlotNum:  slot.ID,
+			RegNum:   slot.Car.RegistrationNumber,
+			Color:    slot.Car.Color,
+			Category: slot.Category,
+			Permit:   slot.Car.Permit,
+			ParkedAt: slot.Car.ParkedAt,
+		})
 	}
 	return result
 }
+
+// findCandidates returns the slots possibly matching the filter.
+func (ims *InMemoryStorage) findCandidates(filter *Filter) []int {
+	var regNums []string
+	regNumCount := ims.regNums.Len()
+	if filter.RegNum != "" {
+		regNums = ims.regNums.WithPrefix(literalPrefix(filter.RegNum))
+		regNumCount = len(regNums)
+	}
+	fromSlot, toSlot := filter.FromSlot, filter.ToSlot
+	if fromSlot <= 0 {
+		fromSlot = 1
+	}
+	if toSlot <= 0 || toSlot > ims.size {
+		toSlot = ims.size
+	}
+
+	if filter.Color != "" {
+		count := ims.slotsByColor.Len(filter.Color)
+		if count <= regNumCount && count <= toSlot-fromSlot+1 {
+			return ims.slotsByColor.Membership(filter.Color)
+		}
+	}
+	if regNumCount <= toSlot-fromSlot+1 {
+		candidates := make([]int, 0, regNumCount)
+		if filter.RegNum == "" {
+			for slotID, slot := range ims.slots {
+				if slot.Car != nil {
+					candidates = append(candidates, slotID)
+				}
+			}
+			return candidates
+		}
+		for _, regNum := range regNums {
+			ims.slotsByRegNum.Each(regNum, func(slotID int) {
+				candidates = append(candidates, slotID)
+			})
+		}
+		return candidates
+	}
+	candidates := make([]int, 0)
+	for slotID := fromSlot; slotID <= toSlot; slotID++ {
+		if slot := ims.slot(slotID); slot != nil && slot.Car != nil {
+			candidates = append(candidates, slotID)
+		}
+	}
+	return candidates
+}
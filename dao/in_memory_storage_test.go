@@ -189,3 +189,219 @@ func TestInMemoryStorage_SlotNumForCarWithRegNumNoCar(t *testing.T) {
 		t.Errorf("() RegNumForCarsWithColor got %v want %v", regNum, expected)
 	}
 }
+
+func TestInMemoryStorage_SetCategory(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	err := storage.SetCategory(2, SlotCategoryEV)
+	if err != nil {
+		t.Errorf("SetCategory() Error %v", err)
+	}
+	slots := storage.Slots()
+	if len(slots) != 1 || slots[0].SlotNum != 2 || slots[0].Category != SlotCategoryEV {
+		t.Errorf("SetCategory() got %+v", slots)
+	}
+}
+
+func TestInMemoryStorage_SetCategoryErrorOutOnExceedingAvailableParking(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	for _, slotID := range []int{0, 3} {
+		if err := storage.SetCategory(slotID, SlotCategoryEV); err != ErrSlotExceedsAvailableParking {
+			t.Errorf("SetCategory() Error got %v want %v", err, ErrSlotExceedsAvailableParking)
+		}
+	}
+}
+
+func TestInMemoryStorage_Close(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	if err := storage.Close(1, "Repainting"); err != nil {
+		t.Errorf("Close() Error %v", err)
+	}
+	if err := storage.Close(1, "Repainting"); err != ErrSlotClosed {
+		t.Errorf("Close() Error got %v want %v", err, ErrSlotClosed)
+	}
+	err := storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	if err != ErrSlotClosed {
+		t.Errorf("Park() Error got %v want %v", err, ErrSlotClosed)
+	}
+	slots := storage.Slots()
+	if len(slots) != 1 || !slots[0].Closed || slots[0].ClosedReason != "Repainting" {
+		t.Errorf("Slots() got %+v", slots)
+	}
+}
+
+func TestInMemoryStorage_Slots(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(10000000)
+	_ = storage.Park(9000000, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	_ = storage.Close(7, "Repainting")
+	_ = storage.SetCategory(42, SlotCategoryEV)
+	_ = storage.Park(3, &Car{Color: "Red", RegistrationNumber: "KA-01-HH-1235"})
+	_, _ = storage.Leave(3)
+
+	slots := make([]int, 0)
+	for _, status := range storage.Slots() {
+		slots = append(slots, status.SlotNum)
+	}
+	if expected := []int{7, 42, 9000000}; !reflect.DeepEqual(slots, expected) {
+		t.Errorf("Slots() got %v want %v", slots, expected)
+	}
+}
+
+func TestInMemoryStorage_CloseShouldNotAllowOccupiedSlot(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	_ = storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	if err := storage.Close(1, "Repainting"); err != ErrSlotAlreadyOccupied {
+		t.Errorf("Close() Error got %v want %v", err, ErrSlotAlreadyOccupied)
+	}
+}
+
+func TestInMemoryStorage_Open(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	if err := storage.Open(1); err != ErrSlotNotClosed {
+		t.Errorf("Open() Error got %v want %v", err, ErrSlotNotClosed)
+	}
+	_ = storage.Close(1, "Repainting")
+	if err := storage.Open(1); err != nil {
+		t.Errorf("Open() Error %v", err)
+	}
+	if err := storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"}); err != nil {
+		t.Errorf("Park() Error %v", err)
+	}
+}
+
+func TestInMemoryStorage_Move(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(3)
+	_ = storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	_ = storage.Park(2, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1235"})
+
+	if err := storage.Move(1, 2); err != ErrSlotAlreadyOccupied {
+		t.Errorf("Move() Error got %v want %v", err, ErrSlotAlreadyOccupied)
+	}
+	if err := storage.Move(3, 1); err != ErrSlotNotOccupied {
+		t.Errorf("Move() Error got %v want %v", err, ErrSlotNotOccupied)
+	}
+	if err := storage.Move(1, 3); err != nil {
+		t.Errorf("Move() Error %v", err)
+	}
+
+	if slotID := storage.SlotNumForCarWithRegNum("KA-01-HH-1234"); slotID != 3 {
+		t.Errorf("SlotNumForCarWithRegNum() got %d want %d", slotID, 3)
+	}
+	expected := []int{2, 3}
+	if slots := storage.SlotNumForCarsWithColor("White"); !reflect.DeepEqual(slots, expected) {
+		t.Errorf("SlotNumForCarsWithColor() got %v want %v", slots, expected)
+	}
+	if slot, _ := storage.Slot(1); slot.Car != nil {
+		t.Errorf("Slot() got %+v want unoccupied slot", slot)
+	}
+}
+
+func TestInMemoryStorage_MoveShouldNotAllowClosedSlot(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	_ = storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	_ = storage.Close(2, "Repainting")
+	if err := storage.Move(1, 2); err != ErrSlotClosed {
+		t.Errorf("Move() Error got %v want %v", err, ErrSlotClosed)
+	}
+}
+
+func TestInMemoryStorage_Find(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(6)
+	_ = storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	_ = storage.Park(2, &Car{Color: "White", RegistrationNumber: "KA-02-HH-9999"})
+	_ = storage.Park(3, &Car{Color: "Black", RegistrationNumber: "KA-01-BB-0001"})
+	_ = storage.Park(5, &Car{Color: "White", RegistrationNumber: "KA-01-P-333", Permit: SlotCategoryEV})
+	_ = storage.Move(5, 6)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{name: "colour", filter: Filter{Color: "White"}, want: []int{1, 2, 6}},
+		{name: "unknown colour", filter: Filter{Color: "Red"}, want: []int{}},
+		{name: "prefix", filter: Filter{RegNum: "KA-01"}, want: []int{1, 3, 6}},
+		{name: "glob", filter: Filter{RegNum: "KA-0?-HH-*"}, want: []int{1, 2}},
+		{name: "slot range", filter: Filter{FromSlot: 2, ToSlot: 5}, want: []int{2, 3}},
+		{name: "combined", filter: Filter{Color: "White", RegNum: "KA-01-*", FromSlot: 2}, want: []int{6}},
+		{name: "permit", filter: Filter{Permit: SlotCategoryEV}, want: []int{6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := make([]int, 0)
+			for _, status := range storage.Find(tt.filter) {
+				slots = append(slots, status.SlotNum)
+			}
+			if !reflect.DeepEqual(slots, tt.want) {
+				t.Errorf("Find() got %v want %v", slots, tt.want)
+			}
+		})
+	}
+
+	_, _ = storage.Leave(1)
+	if found := storage.Find(Filter{RegNum: "KA-01-HH"}); len(found) != 0 {
+		t.Errorf("Find() got %+v want none", found)
+	}
+}
+
+func TestInMemoryStorage_QueriesDoNotCreateIndexEntries(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	_ = storage.Park(1, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	_ = storage.SlotNumForCarsWithColor("Red")
+	_ = storage.SlotNumForCarWithRegNum("KA-01-HH-1235")
+	_, _ = storage.Leave(1)
+	if len(storage.slotsByColor.idx) != 0 || len(storage.slotsByRegNum.idx) != 0 || len(storage.slots) != 0 {
+		t.Errorf("InMemoryStorage holds %d colours, %d registration numbers and %d slots want none",
+			len(storage.slotsByColor.idx), len(storage.slotsByRegNum.idx), len(storage.slots))
+	}
+}
+
+func BenchmarkInMemoryStorage_SetSize(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		storage := InMemoryStorage{}
+		storage.SetSize(10000000)
+	}
+}
+
+func BenchmarkInMemoryStorage_SlotNumForCarWithRegNum(b *testing.B) {
+	storage := InMemoryStorage{}
+	storage.SetSize(10000000)
+	_ = storage.Park(5000000, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		storage.SlotNumForCarWithRegNum("KA-01-HH-1234")
+		storage.SlotNumForCarWithRegNum("KA-01-HH-9999")
+	}
+}
+
+func BenchmarkInMemoryStorage_SlotNumForCarsWithColorNoCar(b *testing.B) {
+	storage := InMemoryStorage{}
+	storage.SetSize(10000000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		storage.SlotNumForCarsWithColor("White")
+	}
+}
+
+func TestInMemoryStorage_ParkAndLeaveErrorOutOnNonPositiveSlot(t *testing.T) {
+	storage := InMemoryStorage{}
+	storage.SetSize(2)
+	if err := storage.Park(0, &Car{Color: "White", RegistrationNumber: "KA-01-HH-1234"}); err != ErrSlotExceedsAvailableParking {
+		t.Errorf("Park() Error got %v want %v", err, ErrSlotExceedsAvailableParking)
+	}
+	if _, err := storage.Leave(-1); err != ErrSlotExceedsAvailableParking {
+		t.Errorf("Leave() Error got %v want %v", err, ErrSlotExceedsAvailableParking)
+	}
+}
@@ -1,32 +1,127 @@
 package dao
 
-import "errors"
+import (
+	"encoding/json"
+	"parking_lot/common"
+	"time"
+)
 
 var (
-	ErrSlotExceedsAvailableParking = errors.New("ERR_SLOT_EXCEEDS_AVAILABLE_PARKING")
-	ErrSlotAlreadyOccupied         = errors.New("ERR_SLOT_ALREADY_OCCUPIED")
-	ErrSlotNotOccupied             = errors.New("ERR_SLOT_NOT_OCCUPIED")
+	ErrSlotExceedsAvailableParking = common.NewError("ERR_SLOT_EXCEEDS_AVAILABLE_PARKING", common.CategoryValidation,
+		"slot number exceeds the size of the parking lot")
+	ErrSlotAlreadyOccupied = common.NewError("ERR_SLOT_ALREADY_OCCUPIED", common.CategoryCapacity,
+		"slot is already occupied")
+	ErrSlotNotOccupied = common.NewError("ERR_SLOT_NOT_OCCUPIED", common.CategoryNotFound,
+		"slot is not occupied")
 	// ErrDuplicateRegNum specifies error that is returned when second car
 	// with the same registration number is attempted to be parked.
-	ErrDuplicateRegNum = errors.New("ERR_DUPLICATE_REG_NUM")
+	ErrDuplicateRegNum = common.NewError("ERR_DUPLICATE_REG_NUM", common.CategoryValidation,
+		"car with the same registration number is already parked")
+	// ErrSlotClosed specifies slot that is out of service.
+	ErrSlotClosed = common.NewError("ERR_SLOT_CLOSED", common.CategoryCapacity,
+		"slot is closed")
+	// ErrSlotNotClosed specifies slot that is expected to be out of service but is not.
+	ErrSlotNotClosed = common.NewError("ERR_SLOT_NOT_CLOSED", common.CategoryValidation,
+		"slot is not closed")
+	// ErrUnknownSlotCategory specifies slot category that is not supported.
+	ErrUnknownSlotCategory = common.NewError("ERR_UNKNOWN_SLOT_CATEGORY", common.CategoryValidation,
+		"slot category must be one of general, accessible, ev, vip, staff")
+)
+
+// SlotCategory tags slots reserved for specific vehicles. Reserved slots
+// are allocated only to the vehicles with the permit of the same category.
+type SlotCategory string
+
+const (
+	SlotCategoryGeneral    SlotCategory = "general"
+	SlotCategoryAccessible SlotCategory = "accessible"
+	SlotCategoryEV         SlotCategory = "ev"
+	SlotCategoryVIP        SlotCategory = "vip"
+	SlotCategoryStaff      SlotCategory = "staff"
 )
 
+// SlotCategories lists all of the slot categories.
+var SlotCategories = []SlotCategory{
+	SlotCategoryGeneral,
+	SlotCategoryAccessible,
+	SlotCategoryEV,
+	SlotCategoryVIP,
+	SlotCategoryStaff,
+}
+
+// ParseSlotCategory returns the slot category with the name.
+func ParseSlotCategory(name string) (SlotCategory, error) {
+	for _, category := range SlotCategories {
+		if string(category) == name {
+			return category, nil
+		}
+	}
+	return "", ErrUnknownSlotCategory.WithDetail("unknown slot category %q, must be one of general, accessible, ev, vip, staff", name)
+}
+
 type Status struct {
-	SlotNum int
-	RegNum  string
-	Color   string
+	SlotNum  int          `json:"slot"`
+	RegNum   string       `json:"registration_number"`
+	Color    string       `json:"colour"`
+	Category SlotCategory `json:"category"`
+	Permit   SlotCategory `json:"permit,omitempty"`
+	// ParkedAt is the time the car was parked at. Zero for the unoccupied
+	// slots, in which case it is omitted from JSON.
+	ParkedAt time.Time `json:"parked_at"`
+	// Closed is set for the slots out of service, ClosedReason tells why.
+	Closed       bool   `json:"closed,omitempty"`
+	ClosedReason string `json:"closed_reason,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Zero ParkedAt is omitted.
+func (s Status) MarshalJSON() ([]byte, error) {
+	// status does not have the methods of Status, so it is marshalled with
+	// the default encoding. Its ParkedAt is shadowed by the outer one.
+	type status Status
+	return json.Marshal(struct {
+		status
+		ParkedAt *time.Time `json:"parked_at,omitempty"`
+	}{status(s), optionalTime(s.ParkedAt)})
 }
 
 // Car is a container struct to hold car details.
 type Car struct {
-	RegistrationNumber string
-	Color              string
+	RegistrationNumber string `json:"registration_number"`
+	Color              string `json:"colour"`
+	// Permit is the category of the reserved slots the car is eligible for.
+	// Empty for the cars without a permit.
+	Permit SlotCategory `json:"permit,omitempty"`
+	// ParkedAt is the time the car was parked at. Omitted from JSON if zero
+	// (Eg: cars restored from the snapshots without the time).
+	ParkedAt time.Time `json:"parked_at"`
+}
+
+// MarshalJSON implements json.Marshaler. Zero ParkedAt is omitted.
+func (c Car) MarshalJSON() ([]byte, error) {
+	type car Car
+	return json.Marshal(struct {
+		car
+		ParkedAt *time.Time `json:"parked_at,omitempty"`
+	}{car(c), optionalTime(c.ParkedAt)})
+}
+
+// optionalTime returns nil for the zero time.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
 // Slot is a container struct to hold a car.
 type Slot struct {
-	ID  int
-	Car *Car
+	ID       int          `json:"id"`
+	Car      *Car         `json:"car"`
+	Category SlotCategory `json:"category,omitempty"`
+	// Closed is set for the slots out of service (Eg: under maintenance).
+	// Closed slots can not be parked at.
+	Closed       bool   `json:"closed,omitempty"`
+	ClosedReason string `json:"closed_reason,omitempty"`
 }
 
 // Storage interface deals with storing parking related information.
@@ -37,12 +132,25 @@ type Storage interface {
 	Park(slotID int, car *Car) error
 	// Leave Un-parks a car. Un-parking a car unoccupies a slot.
 	Leave(slotID int) (*Car, error)
+	// SetCategory tags the slot with the category.
+	SetCategory(slotID int, category SlotCategory) error
+	// Close takes the unoccupied slot out of service.
+	Close(slotID int, reason string) error
+	// Open puts the closed slot back in service.
+	Open(slotID int) error
+	// Move moves the parked car to the unoccupied slot.
+	Move(fromSlotID int, toSlotID int) error
+	// Slot returns a copy of the slot.
+	Slot(slotID int) (Slot, error)
 	// RegNumForCarsWithColor returns list of cars reg numbers with the color
 	RegNumForCarsWithColor(color string) []string
 	// SlotNumForCarsWithColor returns list of slot numbers with the car of specified color
 	SlotNumForCarsWithColor(color string) []int
 	// SlotNumForCarWithRegNum returns slot ID of the car with the specified reg num.
 	SlotNumForCarWithRegNum(color string) int
-	// Returns status of the each occupied and unoccupied slot.
-	Status() []Status
+	// Slots returns status of the occupied, closed and reserved slots only,
+	// ordered by the slot number.
+	Slots() []Status
+	// Find returns status of the occupied slots with the cars matching the filter.
+	Find(filter Filter) []Status
 }
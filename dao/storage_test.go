@@ -0,0 +1,41 @@
+package dao
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCar_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		car  Car
+		want string
+	}{
+		{name: "Without parking time", car: Car{RegistrationNumber: "KA-01-HH-1234", Color: "White"},
+			want: `{"registration_number":"KA-01-HH-1234","colour":"White"}`},
+		{name: "With parking time", car: Car{RegistrationNumber: "KA-01-HH-1234", Color: "White", Permit: SlotCategoryEV,
+			ParkedAt: time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)},
+			want: `{"registration_number":"KA-01-HH-1234","colour":"White","permit":"ev","parked_at":"2020-01-01T09:00:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := json.Marshal(tt.car)
+			if err != nil || string(out) != tt.want {
+				t.Errorf("Marshal() got %s, %v want %s", out, err, tt.want)
+			}
+			var car Car
+			if err := json.Unmarshal(out, &car); err != nil || car != tt.car {
+				t.Errorf("Unmarshal() got %+v, %v want %+v", car, err, tt.car)
+			}
+		})
+	}
+}
+
+func TestStatus_MarshalJSON(t *testing.T) {
+	out, err := json.Marshal(Status{SlotNum: 1, Category: SlotCategoryGeneral})
+	want := `{"slot":1,"registration_number":"","colour":"","category":"general"}`
+	if err != nil || string(out) != want {
+		t.Errorf("Marshal() got %s, %v want %s", out, err, want)
+	}
+}
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Handler handles the events delivered to a subscriber.
+type Handler func(event Event)
+
+// Policy decides what publishing does when the buffer of an asynchronous
+// subscriber is full.
+type Policy int
+
+const (
+	// Block waits until the subscriber makes room in the buffer. Slow
+	// subscribers slow down the publisher.
+	Block Policy = iota
+	// DropNewest discards the event being published.
+	DropNewest
+	// DropOldest discards the oldest buffered event to make room for the
+	// event being published.
+	DropOldest
+)
+
+// Bus delivers the published events to the subscribers. Synchronous
+// subscribers handle the events on the goroutine of the publisher, in the
+// publish order. Asynchronous subscribers handle them on a goroutine of
+// their own, fed through a buffered channel. Bus is safe for concurrent use.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []*Subscription
+	workers     sync.WaitGroup
+}
+
+// NewBus builds and returns a Bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscription is a subscriber registered with the Bus.
+type Subscription struct {
+	// dropped is accessed atomically. Kept first for the 64-bit alignment.
+	dropped uint64
+	bus     *Bus
+	handler Handler
+	types   map[Type]bool
+	// Set for the asynchronous subscribers only.
+	queue  chan Event
+	policy Policy
+	done   chan struct{}
+	stop   sync.Once
+}
+
+// Subscribe registers the handler to be called synchronously with the
+// events of the types, or with all the events if no type is given. Handler
+// runs on the goroutine of the publisher, which waits for it to return.
+func (b *Bus) Subscribe(handler Handler, types ...Type) *Subscription {
+	s := &Subscription{bus: b, handler: handler, types: typeSet(types)}
+	b.add(s)
+	return s
+}
+
+// SubscribeAsync registers the handler to be called on a goroutine of its
+// own with the events of the types, or with all the events if no type is
+// given. Up to buffer events wait for the handler, the policy decides what
+// happens to the events published once the buffer is full.
+func (b *Bus) SubscribeAsync(handler Handler, buffer int, policy Policy, types ...Type) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	s := &Subscription{bus: b, handler: handler, types: typeSet(types), queue: make(chan Event, buffer),
+		policy: policy, done: make(chan struct{})}
+	b.workers.Add(1)
+	go s.work()
+	b.add(s)
+	return s
+}
+
+func typeSet(types []Type) map[Type]bool {
+	if len(types) == 0 {
+		return nil
+	}
+	set := make(map[Type]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return set
+}
+
+func (b *Bus) add(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers = append(b.subscribers, s)
+}
+
+// Publish delivers the event to the subscribers in the order they
+// subscribed.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		if s.types == nil || s.types[event.Type] {
+			s.deliver(event)
+		}
+	}
+}
+
+// Close unsubscribes all the subscribers and waits for the asynchronous
+// ones to handle the events already buffered. Must not be called from a
+// handler.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.subscribers = nil
+	b.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.close()
+	}
+	b.workers.Wait()
+}
+
+// Unsubscribe stops the delivery of the events published from now on.
+// Asynchronous subscribers still handle the events already buffered.
+func (s *Subscription) Unsubscribe() {
+	b := s.bus
+	b.mu.Lock()
+	subscribers := make([]*Subscription, 0, len(b.subscribers))
+	for _, other := range b.subscribers {
+		if other != s {
+			subscribers = append(subscribers, other)
+		}
+	}
+	// Publish iterates over the slice it read, which is never modified.
+	b.subscribers = subscribers
+	b.mu.Unlock()
+	s.close()
+}
+
+// Dropped returns the number of the events discarded as per the policy of
+// the asynchronous subscriber.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) close() {
+	if s.done != nil {
+		s.stop.Do(func() { close(s.done) })
+	}
+}
+
+func (s *Subscription) deliver(event Event) {
+	if s.queue == nil {
+		s.handler(event)
+		return
+	}
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.queue <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.queue <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.queue:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.queue <- event:
+		case <-s.done:
+		}
+	}
+}
+
+// work handles the buffered events until the subscription is closed, then
+// handles the events left in the buffer.
+func (s *Subscription) work() {
+	defer s.bus.workers.Done()
+	for {
+		select {
+		case event := <-s.queue:
+			s.handler(event)
+		case <-s.done:
+			for {
+				select {
+				case event := <-s.queue:
+					s.handler(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
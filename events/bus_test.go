@@ -0,0 +1,127 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBus_Subscribe(t *testing.T) {
+	bus := NewBus()
+	all := make([]Type, 0)
+	bus.Subscribe(func(event Event) { all = append(all, event.Type) })
+	cars := make([]Type, 0)
+	subscription := bus.Subscribe(func(event Event) { cars = append(cars, event.Type) }, TypeCarParked, TypeCarLeft)
+
+	bus.Publish(Event{Type: TypeLotCreated, LotSize: 2})
+	bus.Publish(Event{Type: TypeCarParked, Slot: 1})
+	bus.Publish(Event{Type: TypeLotFull})
+	subscription.Unsubscribe()
+	bus.Publish(Event{Type: TypeCarLeft, Slot: 1})
+
+	if want := []Type{TypeLotCreated, TypeCarParked, TypeLotFull, TypeCarLeft}; !reflect.DeepEqual(all, want) {
+		t.Errorf("Subscribe() got %v want %v", all, want)
+	}
+	if want := []Type{TypeCarParked}; !reflect.DeepEqual(cars, want) {
+		t.Errorf("Subscribe() with types got %v want %v", cars, want)
+	}
+}
+
+func TestBus_SubscribeAsync(t *testing.T) {
+	bus := NewBus()
+	slots := make([]int, 0)
+	bus.SubscribeAsync(func(event Event) { slots = append(slots, event.Slot) }, 2, Block)
+	for slot := 1; slot <= 100; slot++ {
+		bus.Publish(Event{Type: TypeCarParked, Slot: slot})
+	}
+	bus.Close()
+
+	if len(slots) != 100 {
+		t.Fatalf("SubscribeAsync() got %d events want %d", len(slots), 100)
+	}
+	for i, slot := range slots {
+		if slot != i+1 {
+			t.Fatalf("SubscribeAsync() got slot %d at %d want %d", slot, i, i+1)
+		}
+	}
+}
+
+func TestBus_SubscribeAsyncPolicies(t *testing.T) {
+	tests := []struct {
+		policy Policy
+		want   []int
+	}{
+		{policy: DropNewest, want: []int{1, 2, 3}},
+		{policy: DropOldest, want: []int{1, 4, 5}},
+	}
+	for _, test := range tests {
+		bus := NewBus()
+		// Handler is held on the first event until all the events are published.
+		started, release := make(chan struct{}), make(chan struct{})
+		slots := make([]int, 0)
+		subscription := bus.SubscribeAsync(func(event Event) {
+			if event.Slot == 1 {
+				close(started)
+				<-release
+			}
+			slots = append(slots, event.Slot)
+		}, 2, test.policy)
+
+		bus.Publish(Event{Type: TypeCarParked, Slot: 1})
+		<-started
+		for slot := 2; slot <= 5; slot++ {
+			bus.Publish(Event{Type: TypeCarParked, Slot: slot})
+		}
+		close(release)
+		bus.Close()
+
+		if !reflect.DeepEqual(slots, test.want) {
+			t.Errorf("SubscribeAsync(%d) got %v want %v", test.policy, slots, test.want)
+		}
+		if subscription.Dropped() != 2 {
+			t.Errorf("Dropped() got %d want %d", subscription.Dropped(), 2)
+		}
+	}
+}
+
+func TestBus_ConcurrentPublish(t *testing.T) {
+	bus := NewBus()
+	mu := sync.Mutex{}
+	count := 0
+	bus.SubscribeAsync(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+	}, 8, Block)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				bus.Publish(Event{Type: TypeCarParked})
+			}
+		}()
+	}
+	wg.Wait()
+	bus.Close()
+	if count != 800 {
+		t.Errorf("SubscribeAsync() got %d events want %d", count, 800)
+	}
+}
+
+func TestEvent_JSON(t *testing.T) {
+	out, err := json.Marshal(Event{Type: TypeLotNotFull})
+	if err != nil {
+		t.Fatalf("Marshal() got %v", err)
+	}
+	if want := `{"type":"lot_not_full","at":"0001-01-01T00:00:00Z"}`; string(out) != want {
+		t.Errorf("Marshal() got %s want %s", out, want)
+	}
+	event := Event{}
+	if err := json.Unmarshal(out, &event); err != nil || event.Type != TypeLotNotFull {
+		t.Errorf("Unmarshal() got %v, %v want %v", event.Type, err, TypeLotNotFull)
+	}
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"parking_lot/dao"
+	"time"
+)
+
+// Type is the kind of change to the parking lot an event reports.
+type Type int
+
+const (
+	TypeUnknown Type = iota
+	// TypeLotCreated is published when the parking lot is created or
+	// restored from a snapshot.
+	TypeLotCreated
+	// TypeCarParked is published when a car is parked, including the
+	// waitlisted cars parked in the freed slots.
+	TypeCarParked
+	// TypeCarLeft is published when a car leaves its slot.
+	TypeCarLeft
+	// TypeCarMoved is published when a car is moved to another slot.
+	TypeCarMoved
+	// TypeLotFull is published when the last slot available to the cars
+	// without a permit is taken.
+	TypeLotFull
+	// TypeLotNotFull is published when a slot becomes available to the cars
+	// without a permit again after the lot got full.
+	TypeLotNotFull
+	// TypeWatchlistHit is published when a watchlisted car is parked, or is
+	// refused parking in the strict watchlist mode.
+	TypeWatchlistHit
+	// TypeOverstay is published once per stay when a car goes past the
+	// stay limit of its slot.
+	TypeOverstay
+)
+
+// typeNames maps event types to the names used in the serialized events.
+var typeNames = map[Type]string{
+	TypeUnknown:      "unknown",
+	TypeLotCreated:   "lot_created",
+	TypeCarParked:    "car_parked",
+	TypeCarLeft:      "car_left",
+	TypeCarMoved:     "car_moved",
+	TypeLotFull:      "lot_full",
+	TypeLotNotFull:   "lot_not_full",
+	TypeWatchlistHit: "watchlist_hit",
+	TypeOverstay:     "overstay",
+}
+
+// String returns the name of the event type.
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return typeNames[TypeUnknown]
+}
+
+// MarshalText serializes the event type by its name.
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText parses the event type name. Unknown names parse to
+// TypeUnknown.
+func (t *Type) UnmarshalText(text []byte) error {
+	*t = TypeUnknown
+	for eventType, name := range typeNames {
+		if name == string(text) {
+			*t = eventType
+		}
+	}
+	return nil
+}
+
+// Event is a change to the parking lot. Fields not relevant to the type of
+// the event are left zero.
+type Event struct {
+	Type Type      `json:"type"`
+	At   time.Time `json:"at"`
+	// LotSize is the number of slots of the lot created.
+	LotSize int `json:"lot_size,omitempty"`
+	// Slot is the slot the car is parked at, left or moved to.
+	Slot int `json:"slot,omitempty"`
+	// FromSlot is the slot the car is moved from.
+	FromSlot int `json:"from_slot,omitempty"`
+	// Car is the car parked, left, moved or matching the watchlist.
+	Car *dao.Car `json:"car,omitempty"`
+	// Severity and Reason are the watchlist entry the car matched.
+	Severity string `json:"severity,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// Refused is set when the watchlisted car is refused parking.
+	Refused bool `json:"refused,omitempty"`
+	// Limit is the stay limit the car went past and Over the time it stayed
+	// past the limit, in seconds.
+	Limit float64 `json:"limit_seconds,omitempty"`
+	Over  float64 `json:"over_seconds,omitempty"`
+}
@@ -0,0 +1,151 @@
+package palette
+
+import (
+	"bufio"
+	"io"
+	"parking_lot/common"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrUnknownColor specifies colour not present in the palette in the strict mode.
+	ErrUnknownColor = common.NewError("ERR_UNKNOWN_COLOUR", common.CategoryValidation,
+		"colour is not present in the palette")
+	// ErrInvalidPalette specifies palette definition that can not be parsed.
+	ErrInvalidPalette = common.NewError("ERR_INVALID_PALETTE", common.CategoryUsage,
+		"palette definition is not valid")
+)
+
+// Palette canonicalizes colour names. Colours are matched case-insensitively
+// and synonyms (Eg: "Gray") are mapped to their canonical name (Eg: "Grey").
+// In the strict mode, colours not present in the palette are rejected.
+// Otherwise, they are canonicalized to the title case (Eg: "light coral" to
+// "Light Coral").
+type Palette struct {
+	canonical map[string]string // Lower-cased name or synonym - Canonical name mapping
+	strict    bool
+}
+
+// New builds and returns an empty Palette.
+func New() *Palette {
+	return &Palette{canonical: make(map[string]string)}
+}
+
+// Default builds and returns the palette of common car colours.
+func Default() *Palette {
+	p := New()
+	p.Add("White", "Pearl White")
+	p.Add("Black")
+	p.Add("Red")
+	p.Add("Blue")
+	p.Add("Green")
+	p.Add("Yellow")
+	p.Add("Orange")
+	p.Add("Brown")
+	p.Add("Beige")
+	p.Add("Gold", "Golden")
+	p.Add("Silver")
+	p.Add("Grey", "Gray")
+	p.Add("Maroon")
+	p.Add("Purple", "Violet")
+	return p
+}
+
+// Load reads the palette definition from the reader. Each line defines a
+// canonical colour name optionally followed by its synonyms:
+//
+//	Grey: Gray, Slate Grey
+//
+// Empty lines and lines starting with # are ignored.
+func Load(r io.Reader) (*Palette, error) {
+	p := New()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(text, ":", 2)
+		canonical := strings.TrimSpace(parts[0])
+		if canonical == "" {
+			return nil, ErrInvalidPalette.WithDetail("line %d: colour name is empty", line)
+		}
+		synonyms := make([]string, 0)
+		if len(parts) == 2 {
+			for _, synonym := range strings.Split(parts[1], ",") {
+				if synonym = strings.TrimSpace(synonym); synonym != "" {
+					synonyms = append(synonyms, synonym)
+				}
+			}
+		}
+		p.Add(canonical, synonyms...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Add adds the canonical colour name and its synonyms to the palette.
+func (p *Palette) Add(canonical string, synonyms ...string) {
+	canonical = collapse(canonical)
+	p.canonical[strings.ToLower(canonical)] = canonical
+	for _, synonym := range synonyms {
+		p.canonical[strings.ToLower(collapse(synonym))] = canonical
+	}
+}
+
+// SetStrict enables or disables the strict mode.
+func (p *Palette) SetStrict(strict bool) {
+	p.strict = strict
+}
+
+// Canonicalize returns the canonical name of the colour. Returns
+// ErrUnknownColor in the strict mode if the colour is not in the palette.
+func (p *Palette) Canonicalize(color string) (string, error) {
+	color = collapse(color)
+	if canonical, ok := p.canonical[strings.ToLower(color)]; ok {
+		return canonical, nil
+	}
+	if p.strict {
+		return "", ErrUnknownColor.WithDetail("colour %q is not in the palette, expected one of %s",
+			color, strings.Join(p.Colors(), ", "))
+	}
+	return titleCase(color), nil
+}
+
+// Colors returns the sorted canonical colour names of the palette.
+func (p *Palette) Colors() []string {
+	seen := make(map[string]bool)
+	colors := make([]string, 0)
+	for _, canonical := range p.canonical {
+		if !seen[canonical] {
+			seen[canonical] = true
+			colors = append(colors, canonical)
+		}
+	}
+	sort.Strings(colors)
+	return colors
+}
+
+// collapse trims the colour name and collapses repeated whitespaces.
+func collapse(color string) string {
+	return strings.Join(strings.Fields(color), " ")
+}
+
+// titleCase upper-cases the first letter and lower-cases the rest of each word.
+func titleCase(color string) string {
+	words := strings.Fields(strings.ToLower(color))
+	for i, word := range words {
+		// First letter may take several bytes (Eg: "é").
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToTitle(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
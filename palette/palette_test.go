@@ -0,0 +1,64 @@
+package palette
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPalette_Canonicalize(t *testing.T) {
+	p := Default()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "White", want: "White"},
+		{input: "WHITE", want: "White"},
+		{input: "white", want: "White"},
+		{input: "Gray", want: "Grey"},
+		{input: "grey", want: "Grey"},
+		{input: "light  CORAL", want: "Light Coral"},
+		{input: "élan VERT", want: "Élan Vert"},
+		{input: "ÇAMUR", want: "Çamur"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := p.Canonicalize(tt.input)
+			if err != nil || got != tt.want {
+				t.Errorf("Canonicalize() got %q, %v want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPalette_CanonicalizeStrict(t *testing.T) {
+	p := New()
+	p.Add("Grey", "Gray")
+	p.SetStrict(true)
+	if got, err := p.Canonicalize("GRAY"); err != nil || got != "Grey" {
+		t.Errorf("Canonicalize() got %q, %v want %q", got, err, "Grey")
+	}
+	_, err := p.Canonicalize("Light Coral")
+	if !errors.Is(err, ErrUnknownColor) {
+		t.Errorf("Canonicalize() Error got %v want %v", err, ErrUnknownColor)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	p, err := Load(strings.NewReader("# Colours\nGrey: Gray, Slate  Grey\n\nCrimson Red\n"))
+	if err != nil {
+		t.Errorf("Load() Error %v", err)
+	}
+	expected := []string{"Crimson Red", "Grey"}
+	if !reflect.DeepEqual(p.Colors(), expected) {
+		t.Errorf("Colors() got %v want %v", p.Colors(), expected)
+	}
+	if got, _ := p.Canonicalize("slate grey"); got != "Grey" {
+		t.Errorf("Canonicalize() got %q want %q", got, "Grey")
+	}
+
+	if _, err := Load(strings.NewReader(": Gray\n")); !errors.Is(err, ErrInvalidPalette) {
+		t.Errorf("Load() Error got %v want %v", err, ErrInvalidPalette)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build go1.18
+// +build go1.18
+
+package parser
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"parking_lot/common"
+	"testing"
+)
+
+// addFixtureSeeds seeds the corpus with the functional spec fixture and
+// each of its lines.
+func addFixtureSeeds(f *testing.F) {
+	fixture, err := ioutil.ReadFile("../functional_spec/fixtures/file_input.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(fixture)
+	for _, line := range bytes.Split(fixture, []byte("\n")) {
+		f.Add(line)
+	}
+	f.Add([]byte("park KA-01-HH-1234 Crimson Red --permit=ev\nfind --reg=KA-0?-* --slots=1-10\n"))
+}
+
+// argumentCounts lists the number of arguments of the parsed commands.
+// Commands with the optional arguments are not listed.
+var argumentCounts = map[CommandType]int{
+	CommandCreateParkingLot:          1,
+	CommandPark:                      2,
+	CommandLeave:                     1,
+	CommandStatus:                    0,
+	CommandRegNumForCarWithColor:     1,
+	CommandSlotNumForCarWithColor:    1,
+	CommandSlotNumForCarWithRegNum:   1,
+	CommandSetSlotCategory:           2,
+	CommandCloseSlot:                 2,
+	CommandOpenSlot:                  1,
+	CommandParkAt:                    3,
+	CommandMove:                      2,
+	CommandStatusAt:                  1,
+	CommandSlotNumForCarWithRegNumAt: 2,
+	CommandSaveSnapshot:              1,
+	CommandLoadSnapshot:              1,
+	CommandDiffSnapshots:             2,
+	CommandWatchlistAdd:              3,
+	CommandWatchlistRemove:           1,
+	CommandWatchlist:                 0,
+	CommandOverstays:                 0,
+	CommandReserve:                   3,
+	CommandCancelReservation:         1,
+	CommandReservations:              0,
+}
+
+func FuzzNextCommand(f *testing.F) {
+	addFixtureSeeds(f)
+	f.Fuzz(func(t *testing.T, input []byte) {
+		tokenizer := NewTokenizer(bytes.NewReader(input))
+		for {
+			command, err := NextCommand(&tokenizer)
+			if err == io.EOF {
+				return
+			} else if err != nil && common.CategoryOf(err) == common.CategoryInternal {
+				// Failure reading the input (Eg: line too long).
+				return
+			} else if err != nil {
+				continue
+			}
+
+			if command.Type == CommandUnknown {
+				t.Fatalf("NextCommand() returned unknown command without error")
+			}
+			if count, ok := argumentCounts[command.Type]; ok && len(command.Arguments) != count {
+				t.Fatalf("NextCommand() returned %s with %d arguments want %d", command.Type, len(command.Arguments), count)
+			}
+			if command.Options != nil && len(command.Options) == 0 {
+				t.Fatalf("NextCommand() returned empty non-nil options")
+			}
+			for name := range command.Options {
+				supported := false
+				for _, option := range commandOptions[command.Type] {
+					supported = supported || option == name
+				}
+				if !supported {
+					t.Fatalf("NextCommand() returned unsupported option %q of %s", name, command.Type)
+				}
+			}
+		}
+	})
+}
@@ -2,15 +2,18 @@ package parser
 
 import (
 	"bytes"
-	"errors"
+	"parking_lot/common"
 	"strings"
 )
 
 var (
 	// ErrEmptyLineEntry specifies empty line in the input.
-	ErrEmptyLineEntry = errors.New("ERR_LINE_STRING_EMPTY")
-	ErrUnknownCommand = errors.New("ERR_UNKNOWN_COMMAND")
-	ErrIncorrectUsage = errors.New("ERR_INCORRECT_USAGE")
+	ErrEmptyLineEntry = common.NewError("ERR_LINE_STRING_EMPTY", common.CategoryUsage, "empty line")
+	ErrUnknownCommand = common.NewError("ERR_UNKNOWN_COMMAND", common.CategoryUsage, "unknown command")
+	ErrIncorrectUsage = common.NewError("ERR_INCORRECT_USAGE", common.CategoryUsage,
+		"incorrect number of arguments for the command")
+	ErrUnknownOption = common.NewError("ERR_UNKNOWN_OPTION", common.CategoryUsage,
+		"option is not supported by the command")
 )
 
 type CommandType int
@@ -24,8 +27,140 @@ const (
 	CommandRegNumForCarWithColor
 	CommandSlotNumForCarWithColor
 	CommandSlotNumForCarWithRegNum
+	CommandSetSlotCategory
+	CommandFreeSlots
+	CommandCloseSlot
+	CommandOpenSlot
+	CommandParkAt
+	CommandMove
+	CommandWaitlist
+	CommandWaitlistRemove
+	CommandWaitlistCap
+	CommandStats
+	CommandExportStats
+	CommandFind
+	CommandStatusAt
+	CommandSlotNumForCarWithRegNumAt
+	CommandSaveSnapshot
+	CommandLoadSnapshot
+	CommandDiffSnapshots
+	CommandWatchlistAdd
+	CommandWatchlistRemove
+	CommandWatchlist
+	CommandAlerts
+	CommandOverstays
+	CommandReserve
+	CommandCancelReservation
+	CommandReservations
 )
 
+// commandNames maps command types to the names used in the input.
+var commandNames = map[CommandType]string{
+	CommandUnknown:                   "unknown",
+	CommandCreateParkingLot:          "create_parking_lot",
+	CommandPark:                      "park",
+	CommandLeave:                     "leave",
+	CommandStatus:                    "status",
+	CommandRegNumForCarWithColor:     "registration_numbers_for_cars_with_colour",
+	CommandSlotNumForCarWithColor:    "slot_numbers_for_cars_with_colour",
+	CommandSlotNumForCarWithRegNum:   "slot_number_for_registration_number",
+	CommandSetSlotCategory:           "set_slot_category",
+	CommandFreeSlots:                 "free_slots",
+	CommandCloseSlot:                 "close_slot",
+	CommandOpenSlot:                  "open_slot",
+	CommandParkAt:                    "park_at",
+	CommandMove:                      "move",
+	CommandWaitlist:                  "waitlist",
+	CommandWaitlistRemove:            "waitlist_remove",
+	CommandWaitlistCap:               "waitlist_cap",
+	CommandStats:                     "stats",
+	CommandExportStats:               "export_stats",
+	CommandFind:                      "find",
+	CommandStatusAt:                  "status_at",
+	CommandSlotNumForCarWithRegNumAt: "slot_number_for_registration_number_at",
+	CommandSaveSnapshot:              "save_snapshot",
+	CommandLoadSnapshot:              "load_snapshot",
+	CommandDiffSnapshots:             "diff_snapshots",
+	CommandWatchlistAdd:              "watchlist_add",
+	CommandWatchlistRemove:           "watchlist_remove",
+	CommandWatchlist:                 "watchlist",
+	CommandAlerts:                    "alerts",
+	CommandOverstays:                 "overstays",
+	CommandReserve:                   "reserve",
+	CommandCancelReservation:         "cancel_reservation",
+	CommandReservations:              "reservations",
+}
+
+// commandTypes maps the names used in the input to command types.
+var commandTypes = func() map[string]CommandType {
+	types := make(map[string]CommandType, len(commandNames))
+	for commandType, name := range commandNames {
+		if commandType != CommandUnknown {
+			types[name] = commandType
+		}
+	}
+	return types
+}()
+
+// commandOptions lists the options supported by the commands.
+var commandOptions = map[CommandType][]string{
+	CommandPark:   {"permit"},
+	CommandParkAt: {"permit"},
+	CommandFind:   {"colour", "reg", "slots", "permit", "parked-after", "parked-before"},
+}
+
+// commandArguments maps command types to the least and the most number of
+// arguments of the parsed command. Arguments of several words (Eg: colours)
+// are joined into a single argument by the parser.
+var commandArguments = map[CommandType]struct{ min, max int }{
+	CommandCreateParkingLot:          {1, 1},
+	CommandPark:                      {2, 2},
+	CommandLeave:                     {1, 1},
+	CommandStatus:                    {0, 0},
+	CommandRegNumForCarWithColor:     {1, 1},
+	CommandSlotNumForCarWithColor:    {1, 1},
+	CommandSlotNumForCarWithRegNum:   {1, 1},
+	CommandSetSlotCategory:           {2, 2},
+	CommandFreeSlots:                 {0, 1},
+	CommandCloseSlot:                 {2, 2},
+	CommandOpenSlot:                  {1, 1},
+	CommandParkAt:                    {3, 3},
+	CommandMove:                      {2, 2},
+	CommandWaitlist:                  {0, 0},
+	CommandWaitlistRemove:            {1, 1},
+	CommandWaitlistCap:               {1, 1},
+	CommandStats:                     {0, 0},
+	CommandExportStats:               {1, 1},
+	CommandFind:                      {0, 0},
+	CommandStatusAt:                  {1, 1},
+	CommandSlotNumForCarWithRegNumAt: {2, 2},
+	CommandSaveSnapshot:              {1, 1},
+	CommandLoadSnapshot:              {1, 1},
+	CommandDiffSnapshots:             {2, 2},
+	CommandWatchlistAdd:              {3, 3},
+	CommandWatchlistRemove:           {1, 1},
+	CommandWatchlist:                 {0, 0},
+	CommandAlerts:                    {0, 1},
+	CommandOverstays:                 {0, 0},
+	CommandReserve:                   {3, 3},
+	CommandCancelReservation:         {1, 1},
+	CommandReservations:              {0, 0},
+}
+
+// String returns the name of the command as used in the input.
+func (c CommandType) String() string {
+	if name, ok := commandNames[c]; ok {
+		return name
+	}
+	return commandNames[CommandUnknown]
+}
+
+// MarshalText implements encoding.TextMarshaler. Command types are
+// marshalled with their names.
+func (c CommandType) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
 const (
 	// AverageArgumentsPerCommand is the average number of arguments in a
 	// single command. Used to eagerly allocate memory.
@@ -36,6 +171,9 @@ const (
 type Command struct {
 	Type      CommandType
 	Arguments []string
+	// Options holds the "--name=value" arguments following the positional
+	// arguments. Nil if there are no options.
+	Options map[string]string
 }
 
 func NewCommand(command CommandType, args []string) Command {
@@ -62,25 +200,160 @@ func NextCommand(t *Tokenizer) (Command, error) {
 	for _, arg := range cmdAndArgs[1:] {
 		args = append(args, string(arg))
 	}
+	// Options are split only for the commands supporting them, so that free
+	// text arguments of the others (Eg: close_slot reasons) may start with
+	// "--".
+	var options map[string]string
+	if commandType := commandTypes[cmd]; len(commandOptions[commandType]) > 0 {
+		if args, options, err = splitOptions(args); err != nil {
+			return NewCommand(commandType, args), err
+		}
+	}
 
+	var command Command
 	switch cmd {
 	case "create_parking_lot":
-		return parseCommandCreateParkingLot(args)
+		command, err = parseCommandCreateParkingLot(args)
 	case "park":
-		return parseCommandPark(args)
+		command, err = parseCommandPark(args)
 	case "leave":
-		return parseCommandLeave(args)
+		command, err = parseCommandLeave(args)
 	case "status":
-		return parseCommandStatus(args)
+		command, err = parseCommandStatus(args)
 	case "registration_numbers_for_cars_with_colour":
-		return parseCommandRegNumForCarWithColor(args)
+		command, err = parseCommandRegNumForCarWithColor(args)
 	case "slot_numbers_for_cars_with_colour":
-		return parseCommandSlotNumForCarWithColor(args)
+		command, err = parseCommandSlotNumForCarWithColor(args)
 	case "slot_number_for_registration_number":
-		return parseCommandSlotNumForCarWithRegNum(args)
+		command, err = parseCommandSlotNumForCarWithRegNum(args)
+	case "set_slot_category":
+		command, err = parseCommandSetSlotCategory(args)
+	case "free_slots":
+		command, err = parseCommandFreeSlots(args)
+	case "close_slot":
+		command, err = parseCommandCloseSlot(args)
+	case "open_slot":
+		command, err = parseCommandOpenSlot(args)
+	case "park_at":
+		command, err = parseCommandParkAt(args)
+	case "move":
+		command, err = parseCommandMove(args)
+	case "waitlist":
+		command, err = parseCommandWaitlist(args)
+	case "waitlist_remove":
+		command, err = parseCommandWaitlistRemove(args)
+	case "waitlist_cap":
+		command, err = parseCommandWaitlistCap(args)
+	case "stats":
+		command, err = parseCommandStats(args)
+	case "export_stats":
+		command, err = parseCommandExportStats(args)
+	case "find":
+		command, err = parseCommandFind(args)
+	case "status_at":
+		command, err = parseCommandStatusAt(args)
+	case "slot_number_for_registration_number_at":
+		command, err = parseCommandSlotNumForCarWithRegNumAt(args)
+	case "save_snapshot":
+		command, err = parseCommandSaveSnapshot(args)
+	case "load_snapshot":
+		command, err = parseCommandLoadSnapshot(args)
+	case "diff_snapshots":
+		command, err = parseCommandDiffSnapshots(args)
+	case "watchlist_add":
+		command, err = parseCommandWatchlistAdd(args)
+	case "watchlist_remove":
+		command, err = parseCommandWatchlistRemove(args)
+	case "watchlist":
+		command, err = parseCommandWatchlist(args)
+	case "alerts":
+		command, err = parseCommandAlerts(args)
+	case "overstays":
+		command, err = parseCommandOverstays(args)
+	case "reserve":
+		command, err = parseCommandReserve(args)
+	case "cancel_reservation":
+		command, err = parseCommandCancelReservation(args)
+	case "reservations":
+		command, err = parseCommandReservations(args)
 	default:
 		return NewCommand(CommandUnknown, args), ErrUnknownCommand
 	}
+	if err != nil {
+		return command, err
+	}
+	return withOptions(command, options)
+}
+
+// CheckArguments checks the command has as many arguments as the parsed
+// command of its type. Commands built in code are not parsed, so they are
+// checked before they are executed. Returns ErrIncorrectUsage otherwise.
+func CheckArguments(command Command) error {
+	counts, ok := commandArguments[command.Type]
+	if !ok {
+		return nil
+	}
+	if n := len(command.Arguments); n < counts.min || n > counts.max {
+		if counts.min == counts.max {
+			return ErrIncorrectUsage.WithDetail("%s takes %d arguments, got %d", command.Type, counts.min, n)
+		}
+		return ErrIncorrectUsage.WithDetail("%s takes %d to %d arguments, got %d", command.Type, counts.min, counts.max, n)
+	}
+	return nil
+}
+
+// splitOptions splits the arguments into the positional arguments and the
+// options following them. Options are of the form "--name=value" or "--name".
+// Arguments following an option that are not options themselves are joined
+// to its value (Eg: "--colour=Crimson Red").
+func splitOptions(args []string) ([]string, map[string]string, error) {
+	first := -1
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return args, nil, nil
+	}
+
+	options := make(map[string]string)
+	name := ""
+	for _, arg := range args[first:] {
+		if strings.HasPrefix(arg, "--") {
+			parts := strings.SplitN(arg[2:], "=", 2)
+			name = parts[0]
+			if _, ok := options[name]; ok || name == "" {
+				return args[:first], nil, ErrIncorrectUsage.WithDetail("option %q is not valid or is repeated", arg)
+			}
+			options[name] = ""
+			if len(parts) == 2 {
+				options[name] = parts[1]
+			}
+		} else if options[name] == "" {
+			options[name] = arg
+		} else {
+			options[name] = options[name] + " " + arg
+		}
+	}
+	return args[:first], options, nil
+}
+
+// withOptions validates the options against the ones supported by the
+// command and adds them to the command.
+func withOptions(command Command, options map[string]string) (Command, error) {
+	for name := range options {
+		supported := false
+		for _, option := range commandOptions[command.Type] {
+			supported = supported || option == name
+		}
+		if !supported {
+			return command, ErrUnknownOption.WithDetail("option --%s is not supported by %s", name, command.Type)
+		}
+	}
+	command.Options = options
+	return command, nil
 }
 
 // parseCommandCreateParkingLot contains logic to parse create_parking_lot command.
@@ -96,6 +369,7 @@ func parseCommandCreateParkingLot(args []string) (Command, error) {
 // Examples:
 //   1) "park KA-01-HH-1234 White"
 //   2) "park KA-01-HH-1234 Crimson Red"
+//   3) "park KA-01-HH-1234 White --permit=ev"
 func parseCommandPark(args []string) (Command, error) {
 	if len(args) < 2 {
 		return NewCommand(CommandPark, args), ErrIncorrectUsage
@@ -158,3 +432,261 @@ func parseCommandSlotNumForCarWithRegNum(args []string) (Command, error) {
 	}
 	return NewCommand(CommandSlotNumForCarWithRegNum, args), nil
 }
+
+// parseCommandSetSlotCategory contains logic to parse set_slot_category command.
+// Example: "set_slot_category 4 ev"
+func parseCommandSetSlotCategory(args []string) (Command, error) {
+	if len(args) != 2 {
+		return NewCommand(CommandSetSlotCategory, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandSetSlotCategory, args), nil
+}
+
+// parseCommandFreeSlots contains logic to parse free_slots command.
+// Examples:
+//   1) "free_slots"
+//   2) "free_slots ev"
+func parseCommandFreeSlots(args []string) (Command, error) {
+	if len(args) > 1 {
+		return NewCommand(CommandFreeSlots, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandFreeSlots, args), nil
+}
+
+// parseCommandCloseSlot contains logic to parse close_slot command.
+// Examples:
+//   1) "close_slot 4 Repainting"
+//   2) "close_slot 4 Blocked by a fallen tree"
+func parseCommandCloseSlot(args []string) (Command, error) {
+	if len(args) < 2 {
+		return NewCommand(CommandCloseSlot, args), ErrIncorrectUsage
+	}
+	// Join reason separated with space into single argument.
+	reason := strings.Join(args[1:], " ")
+	return NewCommand(CommandCloseSlot, []string{args[0], reason}), nil
+}
+
+// parseCommandOpenSlot contains logic to parse open_slot command.
+// Example: "open_slot 4"
+func parseCommandOpenSlot(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandOpenSlot, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandOpenSlot, args), nil
+}
+
+// parseCommandParkAt contains logic to parse park_at command.
+// Examples:
+//   1) "park_at 4 KA-01-HH-1234 White"
+//   2) "park_at 4 KA-01-HH-1234 Crimson Red --permit=vip"
+func parseCommandParkAt(args []string) (Command, error) {
+	if len(args) < 3 {
+		return NewCommand(CommandParkAt, args), ErrIncorrectUsage
+	}
+
+	// Join Colors separated with space (Eg: "Crimson Red") into single argument.
+	color := strings.Join(args[2:], " ")
+	return NewCommand(CommandParkAt, []string{args[0], args[1], color}), nil
+}
+
+// parseCommandMove contains logic to parse move command.
+// Example: "move 1 4"
+func parseCommandMove(args []string) (Command, error) {
+	if len(args) != 2 {
+		return NewCommand(CommandMove, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandMove, args), nil
+}
+
+// parseCommandWaitlist contains logic to parse waitlist command.
+// Example: "waitlist"
+func parseCommandWaitlist(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandWaitlist, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandWaitlist, nil), nil
+}
+
+// parseCommandWaitlistRemove contains logic to parse waitlist_remove command.
+// Example: "waitlist_remove KA-01-HH-1234"
+func parseCommandWaitlistRemove(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandWaitlistRemove, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandWaitlistRemove, args), nil
+}
+
+// parseCommandWaitlistCap contains logic to parse waitlist_cap command.
+// Example: "waitlist_cap 10"
+func parseCommandWaitlistCap(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandWaitlistCap, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandWaitlistCap, args), nil
+}
+
+// parseCommandStats contains logic to parse stats command.
+// Example: "stats"
+func parseCommandStats(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandStats, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandStats, nil), nil
+}
+
+// parseCommandExportStats contains logic to parse export_stats command.
+// Examples:
+//   1) "export_stats stats.json"
+//   2) "export_stats weekly report.json"
+func parseCommandExportStats(args []string) (Command, error) {
+	if len(args) < 1 {
+		return NewCommand(CommandExportStats, args), ErrIncorrectUsage
+	}
+	// Join path separated with space into single argument.
+	path := strings.Join(args, " ")
+	return NewCommand(CommandExportStats, []string{path}), nil
+}
+
+// parseCommandFind contains logic to parse find command. Filters are given
+// as the options.
+// Examples:
+//   1) "find --colour=White"
+//   2) "find --reg=KA-01-* --slots=1-10"
+//   3) "find --colour=Crimson Red --parked-after=2020-01-01T09:00:00Z"
+func parseCommandFind(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandFind, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandFind, nil), nil
+}
+
+// parseCommandStatusAt contains logic to parse status_at command.
+// Example: "status_at 2020-01-01T14:30:00Z"
+func parseCommandStatusAt(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandStatusAt, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandStatusAt, args), nil
+}
+
+// parseCommandSlotNumForCarWithRegNumAt contains logic to parse slot_number_for_registration_number_at command.
+// Example: "slot_number_for_registration_number_at KA-01-HH-1234 2020-01-01T14:30:00Z"
+func parseCommandSlotNumForCarWithRegNumAt(args []string) (Command, error) {
+	if len(args) != 2 {
+		return NewCommand(CommandSlotNumForCarWithRegNumAt, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandSlotNumForCarWithRegNumAt, args), nil
+}
+
+// parseCommandSaveSnapshot contains logic to parse save_snapshot command.
+// Examples:
+//   1) "save_snapshot lot.json"
+//   2) "save_snapshot shift start.json"
+func parseCommandSaveSnapshot(args []string) (Command, error) {
+	if len(args) < 1 {
+		return NewCommand(CommandSaveSnapshot, args), ErrIncorrectUsage
+	}
+	// Join path separated with space into single argument.
+	path := strings.Join(args, " ")
+	return NewCommand(CommandSaveSnapshot, []string{path}), nil
+}
+
+// parseCommandLoadSnapshot contains logic to parse load_snapshot command.
+// Examples:
+//   1) "load_snapshot lot.json"
+//   2) "load_snapshot shift start.json"
+func parseCommandLoadSnapshot(args []string) (Command, error) {
+	if len(args) < 1 {
+		return NewCommand(CommandLoadSnapshot, args), ErrIncorrectUsage
+	}
+	// Join path separated with space into single argument.
+	path := strings.Join(args, " ")
+	return NewCommand(CommandLoadSnapshot, []string{path}), nil
+}
+
+// parseCommandDiffSnapshots contains logic to parse diff_snapshots command.
+// Example: "diff_snapshots shift-start.json shift-end.json"
+func parseCommandDiffSnapshots(args []string) (Command, error) {
+	if len(args) != 2 {
+		return NewCommand(CommandDiffSnapshots, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandDiffSnapshots, args), nil
+}
+
+// parseCommandWatchlistAdd contains logic to parse watchlist_add command.
+// Examples:
+//   1) "watchlist_add KA-01-HH-1234 high Stolen"
+//   2) "watchlist_add KA-01-HH-1234 blocked Banned after a fight with the staff"
+func parseCommandWatchlistAdd(args []string) (Command, error) {
+	if len(args) < 3 {
+		return NewCommand(CommandWatchlistAdd, args), ErrIncorrectUsage
+	}
+	// Join reason separated with space into single argument.
+	reason := strings.Join(args[2:], " ")
+	return NewCommand(CommandWatchlistAdd, []string{args[0], args[1], reason}), nil
+}
+
+// parseCommandWatchlistRemove contains logic to parse watchlist_remove command.
+// Example: "watchlist_remove KA-01-HH-1234"
+func parseCommandWatchlistRemove(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandWatchlistRemove, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandWatchlistRemove, args), nil
+}
+
+// parseCommandWatchlist contains logic to parse watchlist command.
+// Example: "watchlist"
+func parseCommandWatchlist(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandWatchlist, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandWatchlist, nil), nil
+}
+
+// parseCommandAlerts contains logic to parse alerts command.
+// Examples:
+//   1) "alerts"
+//   2) "alerts KA-01-HH-1234"
+func parseCommandAlerts(args []string) (Command, error) {
+	if len(args) > 1 {
+		return NewCommand(CommandAlerts, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandAlerts, args), nil
+}
+
+// parseCommandOverstays contains logic to parse overstays command.
+// Example: "overstays"
+func parseCommandOverstays(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandOverstays, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandOverstays, nil), nil
+}
+
+// parseCommandReserve contains logic to parse reserve command.
+// Example: "reserve KA-01-HH-1234 2020-01-01T09:00:00Z 2020-01-01T17:00:00Z"
+func parseCommandReserve(args []string) (Command, error) {
+	if len(args) != 3 {
+		return NewCommand(CommandReserve, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandReserve, args), nil
+}
+
+// parseCommandCancelReservation contains logic to parse cancel_reservation command.
+// Example: "cancel_reservation KA-01-HH-1234"
+func parseCommandCancelReservation(args []string) (Command, error) {
+	if len(args) != 1 {
+		return NewCommand(CommandCancelReservation, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandCancelReservation, args), nil
+}
+
+// parseCommandReservations contains logic to parse reservations command.
+// Example: "reservations"
+func parseCommandReservations(args []string) (Command, error) {
+	if len(args) != 0 {
+		return NewCommand(CommandReservations, args), ErrIncorrectUsage
+	}
+	return NewCommand(CommandReservations, nil), nil
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
 	"io"
+	"io/ioutil"
 	"reflect"
 	"strings"
 	"testing"
@@ -109,6 +112,152 @@ func TestNextCommand(t *testing.T) {
 			name: "Fail slot_number_for_registration_number with two arg", tokenizer: NewTokenizer(strings.NewReader("slot_number_for_registration_number KA-01-HH-1234 KA-01-HH-1235\n")),
 			want: NewCommand(CommandSlotNumForCarWithRegNum, []string{"KA-01-HH-1234", "KA-01-HH-1235"}), wantErr: true, wantErrType: ErrIncorrectUsage,
 		},
+		{
+			name: "Parse park with permit", tokenizer: NewTokenizer(strings.NewReader("park KA-01-HH-1234 Crimson Red --permit=ev\n")),
+			want: Command{Type: CommandPark, Arguments: []string{"KA-01-HH-1234", "Crimson Red"}, Options: map[string]string{"permit": "ev"}}, wantErr: false,
+		},
+		{
+			name: "Fails park with unknown option", tokenizer: NewTokenizer(strings.NewReader("park KA-01-HH-1234 White --valet\n")),
+			want: NewCommand(CommandPark, []string{"KA-01-HH-1234", "White"}), wantErr: true, wantErrType: ErrUnknownOption,
+		},
+		{
+			name: "Fails park with repeated option", tokenizer: NewTokenizer(strings.NewReader("park KA-01-HH-1234 White --permit=ev --permit=vip\n")),
+			want: NewCommand(CommandPark, []string{"KA-01-HH-1234", "White"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Fails status with option", tokenizer: NewTokenizer(strings.NewReader("status --permit=ev\n")),
+			want: NewCommand(CommandStatus, []string{"--permit=ev"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse close_slot with reason starting with dashes", tokenizer: NewTokenizer(strings.NewReader("close_slot 4 --do not use-- flooded\n")),
+			want: NewCommand(CommandCloseSlot, []string{"4", "--do not use-- flooded"}), wantErr: false,
+		},
+		{
+			name: "Parse watchlist_add with reason starting with dashes", tokenizer: NewTokenizer(strings.NewReader("watchlist_add KA-01-HH-1234 high --stolen=yes\n")),
+			want: NewCommand(CommandWatchlistAdd, []string{"KA-01-HH-1234", "high", "--stolen=yes"}), wantErr: false,
+		},
+		{
+			name: "Parse set_slot_category", tokenizer: NewTokenizer(strings.NewReader("set_slot_category 4 ev\n")),
+			want: NewCommand(CommandSetSlotCategory, []string{"4", "ev"}), wantErr: false,
+		},
+		{
+			name: "Fail set_slot_category without category", tokenizer: NewTokenizer(strings.NewReader("set_slot_category 4\n")),
+			want: NewCommand(CommandSetSlotCategory, []string{"4"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse free_slots", tokenizer: NewTokenizer(strings.NewReader("free_slots\n")),
+			want: NewCommand(CommandFreeSlots, []string{}), wantErr: false,
+		},
+		{
+			name: "Parse free_slots with category", tokenizer: NewTokenizer(strings.NewReader("free_slots ev\n")),
+			want: NewCommand(CommandFreeSlots, []string{"ev"}), wantErr: false,
+		},
+		{
+			name: "Parse close_slot", tokenizer: NewTokenizer(strings.NewReader("close_slot 4 Blocked by a tree\n")),
+			want: NewCommand(CommandCloseSlot, []string{"4", "Blocked by a tree"}), wantErr: false,
+		},
+		{
+			name: "Fail close_slot without reason", tokenizer: NewTokenizer(strings.NewReader("close_slot 4\n")),
+			want: NewCommand(CommandCloseSlot, []string{"4"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse open_slot", tokenizer: NewTokenizer(strings.NewReader("open_slot 4\n")),
+			want: NewCommand(CommandOpenSlot, []string{"4"}), wantErr: false,
+		},
+		{
+			name: "Fail open_slot without arg", tokenizer: NewTokenizer(strings.NewReader("open_slot\n")),
+			want: NewCommand(CommandOpenSlot, []string{}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse park_at", tokenizer: NewTokenizer(strings.NewReader("park_at 4 KA-01-HH-1234 Crimson Red\n")),
+			want: NewCommand(CommandParkAt, []string{"4", "KA-01-HH-1234", "Crimson Red"}), wantErr: false,
+		},
+		{
+			name: "Fail park_at without color", tokenizer: NewTokenizer(strings.NewReader("park_at 4 KA-01-HH-1234\n")),
+			want: NewCommand(CommandParkAt, []string{"4", "KA-01-HH-1234"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse move", tokenizer: NewTokenizer(strings.NewReader("move 1 4\n")),
+			want: NewCommand(CommandMove, []string{"1", "4"}), wantErr: false,
+		},
+		{
+			name: "Fail move without destination", tokenizer: NewTokenizer(strings.NewReader("move 1\n")),
+			want: NewCommand(CommandMove, []string{"1"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse waitlist", tokenizer: NewTokenizer(strings.NewReader("waitlist\n")),
+			want: NewCommand(CommandWaitlist, nil), wantErr: false,
+		},
+		{
+			name: "Parse waitlist_remove", tokenizer: NewTokenizer(strings.NewReader("waitlist_remove KA-01-HH-1234\n")),
+			want: NewCommand(CommandWaitlistRemove, []string{"KA-01-HH-1234"}), wantErr: false,
+		},
+		{
+			name: "Fail waitlist_cap without capacity", tokenizer: NewTokenizer(strings.NewReader("waitlist_cap\n")),
+			want: NewCommand(CommandWaitlistCap, []string{}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse export_stats", tokenizer: NewTokenizer(strings.NewReader("export_stats weekly report.json\n")),
+			want: NewCommand(CommandExportStats, []string{"weekly report.json"}), wantErr: false,
+		},
+		{
+			name: "Parse find", tokenizer: NewTokenizer(strings.NewReader("find --colour=Crimson Red --reg=KA-01-*\n")),
+			want: Command{Type: CommandFind, Options: map[string]string{"colour": "Crimson Red", "reg": "KA-01-*"}}, wantErr: false,
+		},
+		{
+			name: "Parse status_at", tokenizer: NewTokenizer(strings.NewReader("status_at 2020-01-01T14:30:00Z\n")),
+			want: NewCommand(CommandStatusAt, []string{"2020-01-01T14:30:00Z"}), wantErr: false,
+		},
+		{
+			name:      "Parse slot_number_for_registration_number_at",
+			tokenizer: NewTokenizer(strings.NewReader("slot_number_for_registration_number_at KA-01-HH-1234 2020-01-01T14:30:00Z\n")),
+			want:      NewCommand(CommandSlotNumForCarWithRegNumAt, []string{"KA-01-HH-1234", "2020-01-01T14:30:00Z"}), wantErr: false,
+		},
+		{
+			name:      "Fail slot_number_for_registration_number_at without timestamp",
+			tokenizer: NewTokenizer(strings.NewReader("slot_number_for_registration_number_at KA-01-HH-1234\n")),
+			want:      NewCommand(CommandSlotNumForCarWithRegNumAt, []string{"KA-01-HH-1234"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse save_snapshot", tokenizer: NewTokenizer(strings.NewReader("save_snapshot shift start.json\n")),
+			want: NewCommand(CommandSaveSnapshot, []string{"shift start.json"}), wantErr: false,
+		},
+		{
+			name: "Fail load_snapshot without path", tokenizer: NewTokenizer(strings.NewReader("load_snapshot\n")),
+			want: NewCommand(CommandLoadSnapshot, []string{}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse diff_snapshots", tokenizer: NewTokenizer(strings.NewReader("diff_snapshots start.json end.json\n")),
+			want: NewCommand(CommandDiffSnapshots, []string{"start.json", "end.json"}), wantErr: false,
+		},
+		{
+			name: "Parse watchlist_add", tokenizer: NewTokenizer(strings.NewReader("watchlist_add KA-01-HH-1234 blocked Unpaid fines\n")),
+			want: NewCommand(CommandWatchlistAdd, []string{"KA-01-HH-1234", "blocked", "Unpaid fines"}), wantErr: false,
+		},
+		{
+			name: "Fail watchlist_add without reason", tokenizer: NewTokenizer(strings.NewReader("watchlist_add KA-01-HH-1234 high\n")),
+			want: NewCommand(CommandWatchlistAdd, []string{"KA-01-HH-1234", "high"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse alerts", tokenizer: NewTokenizer(strings.NewReader("alerts KA-01-HH-1234\n")),
+			want: NewCommand(CommandAlerts, []string{"KA-01-HH-1234"}), wantErr: false,
+		},
+		{
+			name: "Fail overstays with arg", tokenizer: NewTokenizer(strings.NewReader("overstays 4h\n")),
+			want: NewCommand(CommandOverstays, []string{"4h"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse reserve", tokenizer: NewTokenizer(strings.NewReader("reserve KA-01-HH-1234 2020-01-01T09:00:00Z 2020-01-01T17:00:00Z\n")),
+			want: NewCommand(CommandReserve, []string{"KA-01-HH-1234", "2020-01-01T09:00:00Z", "2020-01-01T17:00:00Z"}), wantErr: false,
+		},
+		{
+			name: "Fail reserve without end", tokenizer: NewTokenizer(strings.NewReader("reserve KA-01-HH-1234 2020-01-01T09:00:00Z\n")),
+			want: NewCommand(CommandReserve, []string{"KA-01-HH-1234", "2020-01-01T09:00:00Z"}), wantErr: true, wantErrType: ErrIncorrectUsage,
+		},
+		{
+			name: "Parse cancel_reservation", tokenizer: NewTokenizer(strings.NewReader("cancel_reservation KA-01-HH-1234\n")),
+			want: NewCommand(CommandCancelReservation, []string{"KA-01-HH-1234"}), wantErr: false,
+		},
 		{
 			name: "Fail slot_number_for_registration_number without arg", tokenizer: NewTokenizer(strings.NewReader("slot_number_for_registration_number\n")),
 			want: NewCommand(CommandSlotNumForCarWithRegNum, []string{}), wantErr: true, wantErrType: ErrIncorrectUsage,
@@ -121,7 +270,7 @@ func TestNextCommand(t *testing.T) {
 				t.Errorf("NextCommand() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if tt.wantErr && err != nil && err != tt.wantErrType {
+			if tt.wantErr && err != nil && !errors.Is(err, tt.wantErrType) {
 				t.Errorf("NextCommand() got = %v, want %v", err, tt.wantErrType)
 				return
 			}
@@ -131,3 +280,43 @@ func TestNextCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckArguments(t *testing.T) {
+	for commandType := range commandNames {
+		if _, ok := commandArguments[commandType]; !ok && commandType != CommandUnknown {
+			t.Errorf("commandArguments is missing %s", commandType)
+		}
+	}
+	if err := CheckArguments(NewCommand(CommandFreeSlots, []string{"ev"})); err != nil {
+		t.Errorf("CheckArguments() Error %v", err)
+	}
+	if err := CheckArguments(NewCommand(CommandPark, []string{"KA-01-HH-1234"})); !errors.Is(err, ErrIncorrectUsage) {
+		t.Errorf("CheckArguments() Error got %v want %v", err, ErrIncorrectUsage)
+	}
+	if err := CheckArguments(NewCommand(CommandAlerts, []string{"KA-01-HH-1234", "KA-01-HH-1235"})); !errors.Is(err, ErrIncorrectUsage) {
+		t.Errorf("CheckArguments() Error got %v want %v", err, ErrIncorrectUsage)
+	}
+}
+
+// benchmarkInput returns the functional spec fixture repeated up to the
+// number of lines.
+func benchmarkInput(b *testing.B, lines int) []byte {
+	fixture, err := ioutil.ReadFile("../functional_spec/fixtures/file_input.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	fixture = append(bytes.TrimRight(fixture, "\n"), '\n')
+	fixtureLines := bytes.Count(fixture, []byte("\n"))
+	return bytes.Repeat(fixture, lines/fixtureLines+1)
+}
+
+func BenchmarkNextCommand(b *testing.B) {
+	tokenizer := NewTokenizer(bytes.NewReader(benchmarkInput(b, b.N)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NextCommand(&tokenizer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
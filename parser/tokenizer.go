@@ -8,6 +8,7 @@ import (
 // Tokenizer splits input into commands and it's arguments.
 type Tokenizer struct {
 	scanner *bufio.Scanner
+	line    int
 }
 
 // NewTokenizer builds a new Tokenizer to split input into the tokens.
@@ -25,6 +26,7 @@ func (p *Tokenizer) NextToken() ([]byte, error) {
 	tokenAvailable := p.scanner.Scan()
 	err := p.scanner.Err()
 	if tokenAvailable && err == nil {
+		p.line++
 		token := p.scanner.Bytes()
 		return token, err
 	} else if p.scanner.Err() == nil {
@@ -32,3 +34,18 @@ func (p *Tokenizer) NextToken() ([]byte, error) {
 	}
 	return nil, err
 }
+
+// Line returns the line number (starting at 1) of the token last returned
+// by NextToken. Returns 0 if no token has been read yet.
+func (p *Tokenizer) Line() int {
+	return p.line
+}
+
+// Text returns the token last returned by NextToken as a string. Returns an
+// empty string if no token has been read yet.
+func (p *Tokenizer) Text() string {
+	if p.line == 0 {
+		return ""
+	}
+	return p.scanner.Text()
+}
@@ -61,3 +61,37 @@ func TestTokenizer_NextTokenInvocationAfterEOFResultsInEOF(t *testing.T) {
 		t.Errorf("NextToken() got = %v, want %v", err, io.EOF)
 	}
 }
+
+func TestTokenizer_Line(t *testing.T) {
+	p := NewTokenizer(strings.NewReader("create_parking_lot 6\n\nstatus\n"))
+	if p.Line() != 0 {
+		t.Errorf("Line() got = %v, want %v", p.Line(), 0)
+	}
+	for i := 1; i <= 3; i++ {
+		_, _ = p.NextToken()
+		if p.Line() != i {
+			t.Errorf("Line() got = %v, want %v", p.Line(), i)
+		}
+	}
+	_, _ = p.NextToken()
+	if p.Line() != 3 {
+		t.Errorf("Line() after EOF got = %v, want %v", p.Line(), 3)
+	}
+}
+
+func TestTokenizer_Text(t *testing.T) {
+	p := NewTokenizer(strings.NewReader("create_parking_lot 6\n\nstatus\n"))
+	if p.Text() != "" {
+		t.Errorf("Text() got = %q, want %q", p.Text(), "")
+	}
+	for _, want := range []string{"create_parking_lot 6", "", "status"} {
+		_, _ = p.NextToken()
+		if p.Text() != want {
+			t.Errorf("Text() got = %q, want %q", p.Text(), want)
+		}
+	}
+	_, _ = p.NextToken()
+	if p.Text() != "" {
+		t.Errorf("Text() after EOF got = %q, want %q", p.Text(), "")
+	}
+}
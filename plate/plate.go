@@ -0,0 +1,155 @@
+package plate
+
+import (
+	"parking_lot/common"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidRegNum specifies registration number not matching the expected format.
+var ErrInvalidRegNum = common.NewError("ERR_INVALID_REG_NUM", common.CategoryValidation,
+	"registration number is not valid")
+
+// ErrUnknownFormat specifies plate format name that is not supported.
+var ErrUnknownFormat = common.NewError("ERR_UNKNOWN_PLATE_FORMAT", common.CategoryUsage,
+	"plate format must be a comma separated list of in, any")
+
+// Format validates registration numbers of a region and normalizes them to
+// the canonical form. Two registration numbers refer to the same car if and
+// only if their canonical forms are equal.
+type Format interface {
+	// Normalize returns the canonical form of the registration number.
+	// Returns false if the registration number is not valid for the format.
+	Normalize(regNum string) (string, bool)
+	// Expected describes the expected format to the humans.
+	Expected() string
+}
+
+// Indian is the format of the Indian state registration numbers. Canonical
+// form is <state>-<district>-<series>-<number> (Eg: KA-01-HH-1234), where the
+// state is two letters, the district is two digits, the series is up to three
+// letters and is optional, and the number is up to four digits. Letters are
+// case-insensitive and the hyphens are optional on input.
+type Indian struct{}
+
+var (
+	indianGroups    = regexp.MustCompile(`^([A-Z]{2})-([0-9]{1,2})-(?:([A-Z]{1,3})-)?([0-9]{1,4})$`)
+	indianCompacted = regexp.MustCompile(`^([A-Z]{2})([0-9]{1,2})([A-Z]{0,3})([0-9]{1,4})$`)
+)
+
+func (Indian) Normalize(regNum string) (string, bool) {
+	regNum = strings.ToUpper(strings.TrimSpace(regNum))
+	match := indianGroups.FindStringSubmatch(regNum)
+	if match == nil && !strings.Contains(regNum, "-") {
+		match = indianCompacted.FindStringSubmatch(regNum)
+	}
+	if match == nil {
+		return "", false
+	}
+
+	state, district, series, number := match[1], match[2], match[3], match[4]
+	if len(district) == 1 {
+		district = "0" + district
+	}
+	groups := []string{state, district}
+	if series != "" {
+		groups = append(groups, series)
+	}
+	groups = append(groups, number)
+	return strings.Join(groups, "-"), true
+}
+
+func (Indian) Expected() string {
+	return "Indian state format like KA-01-HH-1234"
+}
+
+// Permissive accepts any registration number made of letters, digits and
+// hyphens. Canonical form is upper-cased without leading, trailing or
+// repeated hyphens.
+type Permissive struct{}
+
+func (Permissive) Normalize(regNum string) (string, bool) {
+	builder := strings.Builder{}
+	hyphen := false
+	for _, r := range strings.ToUpper(regNum) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			if hyphen && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			hyphen = false
+			builder.WriteRune(r)
+		case r == '-':
+			hyphen = true
+		default:
+			return "", false
+		}
+	}
+	if builder.Len() == 0 {
+		return "", false
+	}
+	return builder.String(), true
+}
+
+func (Permissive) Expected() string {
+	return "letters, digits and hyphens"
+}
+
+// Chain tries the formats in order. First format accepting the registration
+// number normalizes it.
+type Chain []Format
+
+func (c Chain) Normalize(regNum string) (string, bool) {
+	for _, format := range c {
+		if normalized, ok := format.Normalize(regNum); ok {
+			return normalized, true
+		}
+	}
+	return "", false
+}
+
+func (c Chain) Expected() string {
+	expected := make([]string, 0, len(c))
+	for _, format := range c {
+		expected = append(expected, format.Expected())
+	}
+	return strings.Join(expected, " or ")
+}
+
+// DefaultFormat accepts Indian registration numbers and falls back to the
+// permissive format for the rest.
+var DefaultFormat Format = Chain{Indian{}, Permissive{}}
+
+// formats maps the format names to the formats.
+var formats = map[string]Format{
+	"in":  Indian{},
+	"any": Permissive{},
+}
+
+// Lookup returns the format for a comma separated list of format names
+// (Eg: "in,any"). Formats are tried in the listed order.
+func Lookup(names string) (Format, error) {
+	chain := Chain{}
+	for _, name := range strings.Split(names, ",") {
+		format, ok := formats[strings.TrimSpace(name)]
+		if !ok {
+			return nil, ErrUnknownFormat.WithDetail("unknown plate format %q, must be a comma separated list of in, any", name)
+		}
+		chain = append(chain, format)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+// Normalize returns the canonical form of the registration number as per the
+// format. Returns ErrInvalidRegNum listing the expected format if the
+// registration number is not valid.
+func Normalize(format Format, regNum string) (string, error) {
+	normalized, ok := format.Normalize(regNum)
+	if !ok {
+		return "", ErrInvalidRegNum.WithDetail("registration number %q is not valid, expected %s", regNum, format.Expected())
+	}
+	return normalized, nil
+}
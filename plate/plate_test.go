@@ -0,0 +1,83 @@
+package plate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIndian_Normalize(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOk bool
+	}{
+		{input: "KA-01-HH-1234", want: "KA-01-HH-1234", wantOk: true},
+		{input: "ka-01-hh-1234", want: "KA-01-HH-1234", wantOk: true},
+		{input: "KA01HH1234", want: "KA-01-HH-1234", wantOk: true},
+		{input: "KA-01-P-333", want: "KA-01-P-333", wantOk: true},
+		{input: "KA-1-P-333", want: "KA-01-P-333", wantOk: true},
+		{input: "DL-12-1234", want: "DL-12-1234", wantOk: true},
+		{input: "KA-01HH-1234", want: "", wantOk: false},
+		{input: "K-01-HH-1234", want: "", wantOk: false},
+		{input: "KA-01-HH-12345", want: "", wantOk: false},
+		{input: "ABC123", want: "", wantOk: false},
+		{input: "", want: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := Indian{}.Normalize(tt.input)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Normalize() got %q, %v want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestPermissive_Normalize(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOk bool
+	}{
+		{input: "abc-123", want: "ABC-123", wantOk: true},
+		{input: "--abc--123-", want: "ABC-123", wantOk: true},
+		{input: "abc_123", want: "", wantOk: false},
+		{input: "---", want: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := Permissive{}.Normalize(tt.input)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Normalize() got %q, %v want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	format, err := Lookup("in,any")
+	if err != nil {
+		t.Errorf("Lookup() Error %v", err)
+	}
+	if got, _ := format.Normalize("ka01hh1234"); got != "KA-01-HH-1234" {
+		t.Errorf("Normalize() got %q want %q", got, "KA-01-HH-1234")
+	}
+	if got, _ := format.Normalize("ab-c"); got != "AB-C" {
+		t.Errorf("Normalize() got %q want %q", got, "AB-C")
+	}
+
+	if _, err := Lookup("in,xx"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Lookup() Error got %v want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	_, err := Normalize(Indian{}, "ABC")
+	if !errors.Is(err, ErrInvalidRegNum) {
+		t.Errorf("Normalize() Error got %v want %v", err, ErrInvalidRegNum)
+	}
+	expected := `ERR_INVALID_REG_NUM: registration number "ABC" is not valid, expected Indian state format like KA-01-HH-1234`
+	if err.Error() != expected {
+		t.Errorf("Normalize() Error got %q want %q", err.Error(), expected)
+	}
+}
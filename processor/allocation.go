@@ -1,61 +1,213 @@
 package processor
 
 import (
-	"container/heap"
 	"parking_lot/common"
+	"parking_lot/dao"
 )
 
+// ErrUnknownAllocator specifies allocation strategy that is not supported.
+var ErrUnknownAllocator = common.NewError("ERR_UNKNOWN_ALLOCATOR", common.CategoryUsage,
+	"allocation strategy must be one of nearest, bitmap")
+
 // Allocator is a interface type to deal with the allocating slots for the parking.
+// Free slots are kept in a separate pool for each slot category.
 type Allocator interface {
-	// MarkAsAllocated marks the slot as allocated.
+	// MarkAsAllocated marks the slot returned by SelectCandidate as allocated.
 	MarkAsAllocated()
+	// MarkSlotAsAllocated marks the specific free slot as allocated.
+	MarkSlotAsAllocated(slotID int)
 	// MarkAsAvailable frees up the slot.
 	MarkAsAvailable(slotID int)
-	// SelectCandidate returns the slot next to be allocated.
+	// SelectCandidate returns the general slot next to be allocated.
 	SelectCandidate() int
-	// SetSize sets the size of the parking lot.
+	// SelectCandidateIn returns the slot of the category next to be allocated.
+	// Returns 0 if there are no free slots in the category.
+	SelectCandidateIn(category dao.SlotCategory) int
+	// SetCategory moves the slot to the pool of the category.
+	SetCategory(slotID int, category dao.SlotCategory)
+	// Close takes the free slot out of the allocation until it is opened.
+	Close(slotID int)
+	// Open returns the closed slot to the allocation.
+	Open(slotID int)
+	// Capacity returns the number of the slots of the category not closed.
+	Capacity(category dao.SlotCategory) int
+	// FreeSlots returns the free slots of the category in ascending order.
+	FreeSlots(category dao.SlotCategory) []int
+	// FreeCount returns the number of the free slots of the category.
+	FreeCount(category dao.SlotCategory) int
+	// SetSize sets the size of the parking lot. All slots are general initially.
 	SetSize(size int)
 	// GetSize returns the size of the parking lot.
 	GetSize() int
 }
 
-// NearestAllocator allocates slot nearest to the entrance for the incoming car.
-type NearestAllocator struct {
-	size int
-	heap common.IntMinHeap
+// slotPool is the set of the free slots of a category.
+type slotPool interface {
+	Add(slotID int)
+	AddRange(from int, to int)
+	Remove(slotID int)
+	Contains(slotID int) bool
+	// Min returns the smallest free slot, or -1 if there is none.
+	Min() int
+	Len() int
+	Members() []int
 }
 
-// NewNearestAllocator builds and returns the NearestAllocator
-func NewNearestAllocator() NearestAllocator {
-	n := NearestAllocator{}
-	n.heap = common.IntMinHeap{}
-	heap.Init(&n.heap)
-	return n
+// categoryPools implements the Allocator on the pool of the free slots per
+// slot category. Allocators differ in the pools they keep the slots in.
+type categoryPools struct {
+	size       int
+	pools      map[dao.SlotCategory]slotPool
+	categories map[int]dao.SlotCategory // SlotID - Category mapping of non-general slots
+	closed     map[int]bool
+	// sizes counts the slots of the each category and closedSizes the
+	// closed ones among them.
+	sizes       map[dao.SlotCategory]int
+	closedSizes map[dao.SlotCategory]int
+	newPool     func() slotPool
+}
+
+func newCategoryPools(newPool func() slotPool) categoryPools {
+	return categoryPools{
+		pools:       make(map[dao.SlotCategory]slotPool),
+		categories:  make(map[int]dao.SlotCategory),
+		closed:      make(map[int]bool),
+		sizes:       make(map[dao.SlotCategory]int),
+		closedSizes: make(map[dao.SlotCategory]int),
+		newPool:     newPool,
+	}
+}
+
+func (cp *categoryPools) SetSize(size int) {
+	cp.size = size
+	cp.sizes[dao.SlotCategoryGeneral] = size - len(cp.categories)
+	cp.pool(dao.SlotCategoryGeneral).AddRange(1, size+1)
+}
+
+func (cp *categoryPools) GetSize() int {
+	return cp.size
 }
 
-func (na *NearestAllocator) SetSize(size int) {
-	na.size = size
-	for i := 1; i <= size; i++ {
-		heap.Push(&na.heap, i)
+func (cp *categoryPools) MarkAsAllocated() {
+	pool := cp.pool(dao.SlotCategoryGeneral)
+	if slotID := pool.Min(); slotID > 0 {
+		pool.Remove(slotID)
 	}
 }
 
-func (na *NearestAllocator) GetSize() int {
-	return na.size
+func (cp *categoryPools) MarkSlotAsAllocated(slotID int) {
+	cp.pool(cp.category(slotID)).Remove(slotID)
 }
 
-func (na *NearestAllocator) MarkAsAllocated() {
-	heap.Remove(&na.heap, 0)
+func (cp *categoryPools) MarkAsAvailable(slotID int) {
+	cp.pool(cp.category(slotID)).Add(slotID)
 }
 
-func (na *NearestAllocator) MarkAsAvailable(slotID int) {
-	heap.Push(&na.heap, slotID)
+func (cp *categoryPools) SelectCandidate() int {
+	return cp.SelectCandidateIn(dao.SlotCategoryGeneral)
 }
 
-func (na *NearestAllocator) SelectCandidate() int {
-	top := na.heap.Peek()
-	if top != nil {
-		return top.(int)
+func (cp *categoryPools) SelectCandidateIn(category dao.SlotCategory) int {
+	if slotID := cp.pool(category).Min(); slotID > 0 {
+		return slotID
 	}
 	return 0
 }
+
+func (cp *categoryPools) SetCategory(slotID int, category dao.SlotCategory) {
+	current := cp.category(slotID)
+	if current == category {
+		return
+	}
+
+	// Move the slot between the pools only if it is free.
+	pool := cp.pool(current)
+	if pool.Contains(slotID) {
+		pool.Remove(slotID)
+		cp.pool(category).Add(slotID)
+	}
+	if category == dao.SlotCategoryGeneral {
+		delete(cp.categories, slotID)
+	} else {
+		cp.categories[slotID] = category
+	}
+	cp.sizes[current]--
+	cp.sizes[category]++
+	if cp.closed[slotID] {
+		cp.closedSizes[current]--
+		cp.closedSizes[category]++
+	}
+}
+
+func (cp *categoryPools) Close(slotID int) {
+	category := cp.category(slotID)
+	cp.pool(category).Remove(slotID)
+	if !cp.closed[slotID] {
+		cp.closed[slotID] = true
+		cp.closedSizes[category]++
+	}
+}
+
+func (cp *categoryPools) Open(slotID int) {
+	if !cp.closed[slotID] {
+		return
+	}
+	category := cp.category(slotID)
+	delete(cp.closed, slotID)
+	cp.closedSizes[category]--
+	cp.pool(category).Add(slotID)
+}
+
+func (cp *categoryPools) Capacity(category dao.SlotCategory) int {
+	return cp.sizes[category] - cp.closedSizes[category]
+}
+
+func (cp *categoryPools) FreeSlots(category dao.SlotCategory) []int {
+	return cp.pool(category).Members()
+}
+
+func (cp *categoryPools) FreeCount(category dao.SlotCategory) int {
+	return cp.pool(category).Len()
+}
+
+func (cp *categoryPools) category(slotID int) dao.SlotCategory {
+	if category, ok := cp.categories[slotID]; ok {
+		return category
+	}
+	return dao.SlotCategoryGeneral
+}
+
+func (cp *categoryPools) pool(category dao.SlotCategory) slotPool {
+	pool, ok := cp.pools[category]
+	if !ok {
+		pool = cp.newPool()
+		cp.pools[category] = pool
+	}
+	return pool
+}
+
+// NearestAllocator allocates slot nearest to the entrance for the incoming car.
+// Free slots are kept as the ranges of consecutive slots, so the memory grows
+// with the number of gaps between the free slots rather than the size.
+type NearestAllocator struct {
+	categoryPools
+}
+
+// NewNearestAllocator builds and returns the NearestAllocator
+func NewNearestAllocator() NearestAllocator {
+	return NearestAllocator{newCategoryPools(func() slotPool { return &common.RangeSet{} })}
+}
+
+// NewAllocator builds and returns the allocator for the allocation strategy name.
+func NewAllocator(name string) (Allocator, error) {
+	switch name {
+	case "nearest":
+		allocator := NewNearestAllocator()
+		return &allocator, nil
+	case "bitmap":
+		allocator := NewBitmapAllocator()
+		return &allocator, nil
+	default:
+		return nil, ErrUnknownAllocator.WithDetail("unknown allocation strategy %q, must be one of nearest, bitmap", name)
+	}
+}
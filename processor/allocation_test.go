@@ -1,6 +1,12 @@
 package processor
 
-import "testing"
+import (
+	"math/rand"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"reflect"
+	"testing"
+)
 
 func TestNewNearestAllocator_SelectCandidate(t *testing.T) {
 	allocator := NewNearestAllocator()
@@ -41,3 +47,138 @@ func TestNewNearestAllocator_SelectCandidateAllSlotsUsed(t *testing.T) {
 		t.Errorf("SelectCandidate() got %d want %d", slot, 0)
 	}
 }
+
+func TestNewNearestAllocator_SelectCandidateIn(t *testing.T) {
+	allocator := NewNearestAllocator()
+	allocator.SetSize(6)
+	allocator.SetCategory(1, dao.SlotCategoryAccessible)
+	allocator.SetCategory(5, dao.SlotCategoryEV)
+	allocator.SetCategory(6, dao.SlotCategoryEV)
+
+	if slot := allocator.SelectCandidate(); slot != 2 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 2)
+	}
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryAccessible); slot != 1 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 1)
+	}
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryVIP); slot != 0 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 0)
+	}
+
+	allocator.MarkSlotAsAllocated(5)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryEV); slot != 6 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 6)
+	}
+
+	allocator.MarkAsAvailable(5)
+	expected := []int{5, 6}
+	if slots := allocator.FreeSlots(dao.SlotCategoryEV); !reflect.DeepEqual(slots, expected) {
+		t.Errorf("FreeSlots() got %v want %v", slots, expected)
+	}
+	expected = []int{2, 3, 4}
+	if slots := allocator.FreeSlots(dao.SlotCategoryGeneral); !reflect.DeepEqual(slots, expected) {
+		t.Errorf("FreeSlots() got %v want %v", slots, expected)
+	}
+}
+
+func TestNewNearestAllocator_SetCategoryOfAllocatedSlot(t *testing.T) {
+	allocator := NewNearestAllocator()
+	allocator.SetSize(2)
+	allocator.MarkAsAllocated()
+	allocator.SetCategory(1, dao.SlotCategoryStaff)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryStaff); slot != 0 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 0)
+	}
+
+	// Slot returns to the pool of its new category once freed.
+	allocator.MarkAsAvailable(1)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryStaff); slot != 1 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 1)
+	}
+	if slot := allocator.SelectCandidate(); slot != 2 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 2)
+	}
+}
+
+func TestNewNearestAllocator_CloseOpen(t *testing.T) {
+	allocator := NewNearestAllocator()
+	allocator.SetSize(4)
+	allocator.SetCategory(4, dao.SlotCategoryEV)
+	allocator.Close(1)
+	allocator.MarkAsAllocated()
+	if slot := allocator.SelectCandidate(); slot != 3 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 3)
+	}
+	if capacity := allocator.Capacity(dao.SlotCategoryGeneral); capacity != 2 {
+		t.Errorf("Capacity() got %d want %d", capacity, 2)
+	}
+
+	// Closed slot moved to another category is counted there.
+	allocator.SetCategory(1, dao.SlotCategoryEV)
+	if general, ev := allocator.Capacity(dao.SlotCategoryGeneral), allocator.Capacity(dao.SlotCategoryEV); general != 2 || ev != 1 {
+		t.Errorf("Capacity() got %d general and %d ev want 2 and 1", general, ev)
+	}
+	allocator.Open(1)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryEV); slot != 1 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 1)
+	}
+	if capacity := allocator.Capacity(dao.SlotCategoryEV); capacity != 2 {
+		t.Errorf("Capacity() got %d want %d", capacity, 2)
+	}
+}
+
+func TestNewNearestAllocator_FreeCount(t *testing.T) {
+	allocator := NewNearestAllocator()
+	allocator.SetSize(10000000)
+	for _, slotID := range []int{5, 7, 9999999} {
+		allocator.MarkSlotAsAllocated(slotID)
+	}
+	allocator.SetCategory(3, dao.SlotCategoryEV)
+	if free := allocator.FreeCount(dao.SlotCategoryGeneral); free != 9999996 {
+		t.Errorf("FreeCount() got %d want %d", free, 9999996)
+	}
+	if free := allocator.FreeCount(dao.SlotCategoryEV); free != 1 {
+		t.Errorf("FreeCount() got %d want %d", free, 1)
+	}
+	// Free slots are kept as the ranges between the allocated and the
+	// reserved slots.
+	if ranges := allocator.pool(dao.SlotCategoryGeneral).(*common.RangeSet).Ranges(); ranges != 5 {
+		t.Errorf("Ranges() got %d want %d", ranges, 5)
+	}
+}
+
+// benchmarkAllocatorChurn parks and leaves cars at random on the lot that is
+// kept about half full.
+func benchmarkAllocatorChurn(b *testing.B, allocator Allocator) {
+	const size = 100000
+	random := rand.New(rand.NewSource(1))
+	allocator.SetSize(size)
+	occupied := make([]int, 0, size)
+	for len(occupied) < size/2 {
+		occupied = append(occupied, allocator.SelectCandidate())
+		allocator.MarkAsAllocated()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if random.Intn(2) == 0 && len(occupied) < size {
+			occupied = append(occupied, allocator.SelectCandidate())
+			allocator.MarkAsAllocated()
+		} else if len(occupied) > 0 {
+			j := random.Intn(len(occupied))
+			allocator.MarkAsAvailable(occupied[j])
+			occupied[j] = occupied[len(occupied)-1]
+			occupied = occupied[:len(occupied)-1]
+		}
+	}
+}
+
+func BenchmarkNearestAllocator_Churn(b *testing.B) {
+	allocator := NewNearestAllocator()
+	benchmarkAllocatorChurn(b, &allocator)
+}
+
+func BenchmarkBitmapAllocator_Churn(b *testing.B) {
+	allocator := NewBitmapAllocator()
+	benchmarkAllocatorChurn(b, &allocator)
+}
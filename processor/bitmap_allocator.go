@@ -0,0 +1,17 @@
+package processor
+
+import (
+	"parking_lot/common"
+)
+
+// BitmapAllocator allocates slot nearest to the entrance for the incoming
+// car, same as the NearestAllocator. Free slots are kept as a bit per slot,
+// which makes it suitable for the very large parking lots.
+type BitmapAllocator struct {
+	categoryPools
+}
+
+// NewBitmapAllocator builds and returns the BitmapAllocator
+func NewBitmapAllocator() BitmapAllocator {
+	return BitmapAllocator{newCategoryPools(func() slotPool { return &common.Bitmap{} })}
+}
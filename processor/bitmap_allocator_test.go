@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"parking_lot/dao"
+	"reflect"
+	"testing"
+)
+
+func TestBitmapAllocator_SelectCandidate(t *testing.T) {
+	allocator := NewBitmapAllocator()
+	allocator.SetSize(130)
+	for _, expected := range []int{1, 2, 3} {
+		if slot := allocator.SelectCandidate(); slot != expected {
+			t.Errorf("SelectCandidate() got %d want %d", slot, expected)
+		}
+		allocator.MarkAsAllocated()
+	}
+	allocator.MarkAsAvailable(2)
+	if slot := allocator.SelectCandidate(); slot != 2 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 2)
+	}
+
+	for slotID := 1; slotID <= 130; slotID++ {
+		allocator.MarkSlotAsAllocated(slotID)
+	}
+	if slot := allocator.SelectCandidate(); slot != 0 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 0)
+	}
+}
+
+func TestBitmapAllocator_SelectCandidateIn(t *testing.T) {
+	allocator := NewBitmapAllocator()
+	allocator.SetSize(6)
+	allocator.SetCategory(1, dao.SlotCategoryAccessible)
+	allocator.SetCategory(5, dao.SlotCategoryEV)
+	allocator.SetCategory(6, dao.SlotCategoryEV)
+
+	if slot := allocator.SelectCandidate(); slot != 2 {
+		t.Errorf("SelectCandidate() got %d want %d", slot, 2)
+	}
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryVIP); slot != 0 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 0)
+	}
+	allocator.MarkSlotAsAllocated(5)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryEV); slot != 6 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 6)
+	}
+
+	// Slot returns to the pool of its new category once freed.
+	allocator.SetCategory(5, dao.SlotCategoryStaff)
+	allocator.MarkAsAvailable(5)
+	if slot := allocator.SelectCandidateIn(dao.SlotCategoryStaff); slot != 5 {
+		t.Errorf("SelectCandidateIn() got %d want %d", slot, 5)
+	}
+	expected := []int{2, 3, 4}
+	if slots := allocator.FreeSlots(dao.SlotCategoryGeneral); !reflect.DeepEqual(slots, expected) {
+		t.Errorf("FreeSlots() got %v want %v", slots, expected)
+	}
+}
+
+func benchmarkAllocatorSetSize(b *testing.B, newAllocator func() Allocator) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		newAllocator().SetSize(1000000)
+	}
+}
+
+func BenchmarkNearestAllocator_SetSize(b *testing.B) {
+	benchmarkAllocatorSetSize(b, func() Allocator {
+		allocator := NewNearestAllocator()
+		return &allocator
+	})
+}
+
+func BenchmarkBitmapAllocator_SetSize(b *testing.B) {
+	benchmarkAllocatorSetSize(b, func() Allocator {
+		allocator := NewBitmapAllocator()
+		return &allocator
+	})
+}
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"sync"
+)
+
+// Diagnostic is a problem found in a single line of the input by Check.
+type Diagnostic struct {
+	Line int
+	Err  error
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s", d.Line, d.Err.Error())
+}
+
+// writesFiles lists the commands that write to the file system. Checker only
+// checks their syntax.
+var writesFiles = map[parser.CommandType]bool{
+	parser.CommandExportStats:  true,
+	parser.CommandSaveSnapshot: true,
+}
+
+// readsFiles lists the commands that read from the file system.
+var readsFiles = map[parser.CommandType]bool{
+	parser.CommandLoadSnapshot:  true,
+	parser.CommandDiffSnapshots: true,
+}
+
+// readOnly lists the commands that never change the state of the parking
+// lot.
+var readOnly = map[parser.CommandType]bool{
+	parser.CommandStatus:                    true,
+	parser.CommandRegNumForCarWithColor:     true,
+	parser.CommandSlotNumForCarWithColor:    true,
+	parser.CommandSlotNumForCarWithRegNum:   true,
+	parser.CommandFreeSlots:                 true,
+	parser.CommandWaitlist:                  true,
+	parser.CommandStats:                     true,
+	parser.CommandExportStats:               true,
+	parser.CommandFind:                      true,
+	parser.CommandStatusAt:                  true,
+	parser.CommandSlotNumForCarWithRegNumAt: true,
+	parser.CommandSaveSnapshot:              true,
+	parser.CommandDiffSnapshots:             true,
+	parser.CommandWatchlist:                 true,
+	parser.CommandAlerts:                    true,
+	parser.CommandOverstays:                 true,
+	parser.CommandReservations:              true,
+}
+
+// ChangesState returns false if the command never changes the state of the
+// parking lot. The persistent state need not be saved after such commands.
+func ChangesState(commandType parser.CommandType) bool {
+	return !readOnly[commandType]
+}
+
+// AccessesFiles returns true if the command reads from or writes to the file
+// system. Such commands must not be executed on behalf of remote clients.
+func AccessesFiles(commandType parser.CommandType) bool {
+	return writesFiles[commandType] || readsFiles[commandType]
+}
+
+// Checker simulates inputs against a scratch InMemoryStorage and
+// NearestAllocator. The scratch state is carried over between the inputs
+// checked by the same Checker.
+type Checker struct {
+	executor Executor
+}
+
+// NewChecker builds and returns the Checker with an empty scratch state.
+// Options configure the scratch Executor the same way as the real one.
+func NewChecker(options ...Option) *Checker {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	return &Checker{executor: NewExecutor(&sync.Mutex{}, &allocator, &storage, options...)}
+}
+
+// Restore seeds the scratch state with the snapshot.
+func (c *Checker) Restore(snapshot Snapshot) error {
+	return c.executor.Restore(snapshot)
+}
+
+// Check parses the whole input with a fresh Checker. See Checker.Check.
+func Check(tokenizer *parser.Tokenizer) ([]Diagnostic, error) {
+	return NewChecker().Check(tokenizer)
+}
+
+// Check parses the whole input and reports every syntax error along with its
+// line number. Commands that parse are simulated against the scratch state
+// to report semantic problems as well (Eg: parking before the lot is created,
+// leaving an empty slot or duplicate registrations). Check never touches any
+// state other than the scratch one. Returned error is non-nil only if reading
+// the input fails.
+func (c *Checker) Check(tokenizer *parser.Tokenizer) ([]Diagnostic, error) {
+	diagnostics := make([]Diagnostic, 0)
+	for {
+		command, err := parser.NextCommand(tokenizer)
+		if err == io.EOF {
+			return diagnostics, nil
+		} else if err != nil && common.CategoryOf(err) == common.CategoryInternal {
+			// Failure reading the input rather than a syntax error.
+			return diagnostics, err
+		} else if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{Line: tokenizer.Line(), Err: err})
+			continue
+		}
+
+		if writesFiles[command.Type] {
+			// Simulating the command would write to the file system.
+			continue
+		}
+		if _, err := c.executor.Execute(command); err != nil {
+			diagnostics = append(diagnostics, Diagnostic{Line: tokenizer.Line(), Err: err})
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	input := strings.Join([]string{
+		"park KA-01-HH-1234 White",
+		"create_parking_lot 3",
+		"park KA-01-HH-1234 White",
+		"park KA-01-HH-1234 Red",
+		"leave 2",
+		"unknown_command",
+		"status 4",
+		"",
+		"status",
+	}, "\n")
+	tokenizer := parser.NewTokenizer(strings.NewReader(input))
+
+	diagnostics, err := Check(&tokenizer)
+	if err != nil {
+		t.Errorf("Check() Error %v", err)
+	}
+
+	expected := []Diagnostic{
+		{Line: 1, Err: ErrParkingLotSizeNotSet},
+		{Line: 4, Err: dao.ErrDuplicateRegNum},
+		{Line: 5, Err: dao.ErrSlotNotOccupied},
+		{Line: 6, Err: parser.ErrUnknownCommand},
+		{Line: 7, Err: parser.ErrIncorrectUsage},
+		{Line: 8, Err: parser.ErrEmptyLineEntry},
+	}
+	if !reflect.DeepEqual(diagnostics, expected) {
+		t.Errorf("Check() got %v want %v", diagnostics, expected)
+	}
+}
+
+func TestCheck_NoProblems(t *testing.T) {
+	tokenizer := parser.NewTokenizer(strings.NewReader("create_parking_lot 1\npark KA-01-HH-1234 White\n"))
+	diagnostics, err := Check(&tokenizer)
+	if err != nil || len(diagnostics) != 0 {
+		t.Errorf("Check() got %v, %v want no diagnostics", diagnostics, err)
+	}
+}
@@ -0,0 +1,802 @@
+package processor
+
+import (
+	"os"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"parking_lot/palette"
+	"parking_lot/parser"
+	"parking_lot/plate"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Executor executes parsed commands against the parking lot state and
+// returns structured results. It does not deal with the textual input or
+// output, which makes it usable by programs that build commands in code.
+type Executor struct {
+	mutex     *sync.Mutex
+	allocator Allocator
+	storage   dao.Storage
+	plates    plate.Format
+	colors    *palette.Palette
+	overflow  []dao.SlotCategory
+	waitlist  *Waitlist
+	clock     Clock
+	stats     *statsRecorder
+	history   *history
+	events    *events.Bus
+	watchlist *watchlist
+	overstays overstayTracker
+	// reserving wraps the allocator passed, holding back the general slots
+	// for the reservations.
+	reserving *reservingAllocator
+	// full is set while there are no slots for the cars without a permit.
+	full bool
+}
+
+// defaultPalette is shared by the executors built without WithPalette.
+// Executor never modifies the palette.
+var defaultPalette = palette.Default()
+
+// Option configures the optional behaviour of the Executor.
+type Option func(e *Executor)
+
+// WithPlateFormat sets the format registration numbers are validated and
+// normalized with. Defaults to plate.DefaultFormat.
+func WithPlateFormat(format plate.Format) Option {
+	return func(e *Executor) {
+		e.plates = format
+	}
+}
+
+// WithPalette sets the palette colours are canonicalized with. Defaults to
+// palette.Default.
+func WithPalette(p *palette.Palette) Option {
+	return func(e *Executor) {
+		e.colors = p
+	}
+}
+
+// WithOverflow sets the categories of the reserved slots that are allocated
+// to the cars without a permit once the general slots run out. By default,
+// reserved slots are never allocated to the cars without a permit.
+func WithOverflow(categories ...dao.SlotCategory) Option {
+	return func(e *Executor) {
+		e.overflow = categories
+	}
+}
+
+// WithWaitlistCapacity enables the waitlist of the cars arriving at the full
+// parking lot and caps its length. Defaults to 0, which disables the waitlist.
+func WithWaitlistCapacity(capacity int) Option {
+	return func(e *Executor) {
+		e.waitlist.capacity = capacity
+	}
+}
+
+// WithClock sets the clock the parking and leaving times are read from.
+// Defaults to time.Now.
+func WithClock(clock Clock) Option {
+	return func(e *Executor) {
+		e.clock = clock
+	}
+}
+
+// WithEvents sets the bus the changes to the parking lot are published to.
+// Events are published while the Executor is locked, in the order of the
+// changes. Synchronous subscribers must not call the Executor. By default,
+// events are not published.
+func WithEvents(bus *events.Bus) Option {
+	return func(e *Executor) {
+		e.events = bus
+	}
+}
+
+// WithWatchlistStrict refuses parking to the cars watchlisted with the
+// blocked severity. By default, watchlisted cars are parked and only raise
+// the alert.
+func WithWatchlistStrict(strict bool) Option {
+	return func(e *Executor) {
+		e.watchlist.strict = strict
+	}
+}
+
+// NewExecutor builds and returns the Executor operating on the allocator
+// and storage passed. Mutex guards concurrent access to the both of them.
+func NewExecutor(mutex *sync.Mutex, allocator Allocator, s dao.Storage, options ...Option) Executor {
+	e := Executor{
+		mutex:     mutex,
+		allocator: allocator,
+		storage:   s,
+		plates:    plate.DefaultFormat,
+		colors:    defaultPalette,
+		waitlist:  &Waitlist{},
+		clock:     time.Now,
+		stats:     newStatsRecorder(),
+		history:   newHistory(),
+		watchlist: &watchlist{},
+		reserving: &reservingAllocator{grace: DefaultReservationGrace},
+	}
+	for _, option := range options {
+		option(&e)
+	}
+	e.reserving.Allocator = allocator
+	e.reserving.clock = e.clock
+	e.allocator = e.reserving
+	return e
+}
+
+// Execute runs a single command and returns its result.
+func (e *Executor) Execute(command parser.Command) (Result, error) {
+	// Use mutex. Though not necessarily required in this particular example
+	// as stdin I suppose writing to stdin wouldn't be concurrent (due to interleaving)
+	// Adding it here as in real-world, a parking lot might have multiple entry and exit
+	// point which may lead to concurrent access.
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	raised := e.watchlist.raised
+	result, err := e.execute(command)
+	result.Alerts = e.watchlist.since(raised)
+	e.publishFull()
+	return result, err
+}
+
+func (e *Executor) execute(command parser.Command) (Result, error) {
+	result := Result{Command: command.Type}
+
+	// IMPORTANT:
+	// Number of arguments is checked here, as the commands built in code are
+	// not validated by the parser. In the switch statement below, argument
+	// slice indices can be safely used without additional bound checks.
+	if err := parser.CheckArguments(command); err != nil {
+		return result, err
+	}
+	switch command.Type {
+	case parser.CommandCreateParkingLot:
+		size, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		} else if size <= 0 {
+			return result, ErrParkingLotSizeInvalid
+		} else if e.allocator.GetSize() > 0 {
+			return result, ErrParkingLotSizeAlreadySet
+		}
+
+		e.allocator.SetSize(int(size))
+		e.storage.SetSize(int(size))
+		e.publishLotCreated(int(size))
+		result.LotSize = int(size)
+		return result, nil
+	case parser.CommandPark:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		car, err := e.newCar(command.Arguments[0], command.Arguments[1], command.Options)
+		if err != nil {
+			return result, err
+		}
+		if err := e.screen(&car); err != nil {
+			return result, err
+		}
+		// Claiming the reservation releases its hold, so the car gets the
+		// capacity held for it.
+		reservation, reserved := e.reserving.claim(car.RegistrationNumber, car.ParkedAt)
+		slotID := e.selectCandidate(car.Permit)
+		if slotID == 0 {
+			if reserved {
+				e.reserving.unclaim(reservation)
+			}
+			result.Full = true
+			result.WaitlistPosition, err = e.enqueue(car)
+			return result, err
+		}
+		err = e.storage.Park(slotID, &car)
+		if err != nil {
+			if reserved {
+				e.reserving.unclaim(reservation)
+			}
+			return result, err
+		}
+		e.allocator.MarkSlotAsAllocated(slotID)
+		e.parked(slotID, &car)
+		result.Slot = slotID
+		result.Reserved = reserved
+		return result, nil
+	case parser.CommandParkAt:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		car, err := e.newCar(command.Arguments[1], command.Arguments[2], command.Options)
+		if err != nil {
+			return result, err
+		}
+		if err := e.screen(&car); err != nil {
+			return result, err
+		}
+		if err := e.checkEligible(int(slotID), &car); err != nil {
+			return result, err
+		}
+		var reservation Reservation
+		reserved := false
+		if e.isFreeGeneral(int(slotID)) {
+			// Car with a reservation takes the capacity held for it.
+			if reservation, reserved = e.reserving.claim(car.RegistrationNumber, car.ParkedAt); !reserved {
+				if err := e.checkHeld(int(slotID), car.ParkedAt); err != nil {
+					return result, err
+				}
+			}
+		}
+		if err := e.storage.Park(int(slotID), &car); err != nil {
+			if reserved {
+				e.reserving.unclaim(reservation)
+			}
+			return result, err
+		}
+		e.allocator.MarkSlotAsAllocated(int(slotID))
+		e.parked(int(slotID), &car)
+		result.Slot = int(slotID)
+		result.Reserved = reserved
+		return result, nil
+	case parser.CommandMove:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		fromSlotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		toSlotID, err := strconv.ParseInt(command.Arguments[1], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		from, err := e.storage.Slot(int(fromSlotID))
+		if err != nil {
+			return result, err
+		}
+		if from.Car == nil {
+			return result, dao.ErrSlotNotOccupied
+		}
+		if err := e.checkEligible(int(toSlotID), from.Car); err != nil {
+			return result, err
+		}
+		// Moving within the general slots leaves as many of them free. Clock
+		// is not read unless there are reservations.
+		if len(e.reserving.reservations) > 0 && from.Category != dao.SlotCategoryGeneral && e.isFreeGeneral(int(toSlotID)) {
+			if err := e.checkHeld(int(toSlotID), e.clock()); err != nil {
+				return result, err
+			}
+		}
+		if err := e.storage.Move(int(fromSlotID), int(toSlotID)); err != nil {
+			return result, err
+		}
+		e.allocator.MarkSlotAsAllocated(int(toSlotID))
+		e.allocator.MarkAsAvailable(int(fromSlotID))
+		e.moved(int(fromSlotID), int(toSlotID), from.Car)
+		result.FromSlot = int(fromSlotID)
+		result.Slot = int(toSlotID)
+		result.Admitted, err = e.admit(int(fromSlotID))
+		return result, err
+	case parser.CommandLeave:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		car, err := e.storage.Leave(int(slotID))
+		if err != nil {
+			return result, err
+		}
+		e.allocator.MarkAsAvailable(int(slotID))
+		e.left(int(slotID), car)
+		result.Slot = int(slotID)
+		result.Admitted, err = e.admit(int(slotID))
+		return result, err
+	case parser.CommandStatus:
+		result.Status = e.storage.Slots()
+		return result, nil
+	case parser.CommandRegNumForCarWithColor:
+		color, err := e.colors.Canonicalize(command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		result.RegNums = e.storage.RegNumForCarsWithColor(color)
+		return result, nil
+	case parser.CommandSlotNumForCarWithColor:
+		color, err := e.colors.Canonicalize(command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		result.Slots = e.storage.SlotNumForCarsWithColor(color)
+		return result, nil
+	case parser.CommandSlotNumForCarWithRegNum:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		result.Slot = e.storage.SlotNumForCarWithRegNum(regNum)
+		return result, nil
+	case parser.CommandSetSlotCategory:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		category, err := dao.ParseSlotCategory(command.Arguments[1])
+		if err != nil {
+			return result, err
+		}
+		if err := e.storage.SetCategory(int(slotID), category); err != nil {
+			return result, err
+		}
+		e.allocator.SetCategory(int(slotID), category)
+		result.Slot = int(slotID)
+		result.Category = category
+		return result, nil
+	case parser.CommandFreeSlots:
+		if len(command.Arguments) == 1 {
+			category, err := dao.ParseSlotCategory(command.Arguments[0])
+			if err != nil {
+				return result, err
+			}
+			slots := e.allocator.FreeSlots(category)
+			result.FreeSlots = []FreeSlots{{Category: category, Count: len(slots), Slots: slots}}
+			return result, nil
+		}
+		// Only the counts are shown, which the allocator keeps without
+		// listing the slots.
+		for _, category := range dao.SlotCategories {
+			result.FreeSlots = append(result.FreeSlots, FreeSlots{
+				Category: category,
+				Count:    e.allocator.FreeCount(category),
+			})
+		}
+		return result, nil
+	case parser.CommandCloseSlot:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		if err := e.storage.Close(int(slotID), command.Arguments[1]); err != nil {
+			return result, err
+		}
+		e.allocator.Close(int(slotID))
+		result.Slot = int(slotID)
+		result.Reason = command.Arguments[1]
+		return result, nil
+	case parser.CommandOpenSlot:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrInvalidSlotID
+		}
+		if err := e.storage.Open(int(slotID)); err != nil {
+			return result, err
+		}
+		e.allocator.Open(int(slotID))
+		result.Slot = int(slotID)
+		result.Admitted, err = e.admit(int(slotID))
+		return result, err
+	case parser.CommandWaitlist:
+		result.Waitlist = e.waitlist.Cars()
+		return result, nil
+	case parser.CommandWaitlistRemove:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		if err := e.waitlist.Remove(regNum); err != nil {
+			return result, err
+		}
+		result.RegNum = regNum
+		return result, nil
+	case parser.CommandWaitlistCap:
+		capacity, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+		if err != nil {
+			return result, ErrWaitlistCapacityInvalid
+		}
+		if err := e.waitlist.SetCapacity(int(capacity)); err != nil {
+			return result, err
+		}
+		result.WaitlistCapacity = int(capacity)
+		return result, nil
+	case parser.CommandStats:
+		stats := e.stats.stats(e.allocator)
+		result.Stats = &stats
+		return result, nil
+	case parser.CommandExportStats:
+		file, err := os.Create(command.Arguments[0])
+		if err != nil {
+			return result, ErrStatsNotWritable.WithDetail("%s", err.Error())
+		}
+		err = WriteStats(file, e.stats.stats(e.allocator))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return result, ErrStatsNotWritable.WithDetail("%s", err.Error())
+		}
+		result.Path = command.Arguments[0]
+		return result, nil
+	case parser.CommandFind:
+		filter, err := e.newFilter(command.Options)
+		if err != nil {
+			return result, err
+		}
+		result.Found = e.storage.Find(filter)
+		return result, nil
+	case parser.CommandStatusAt:
+		at, err := parseTimestamp(command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		result.Status, err = e.history.statusAt(at)
+		return result, err
+	case parser.CommandSlotNumForCarWithRegNumAt:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		at, err := parseTimestamp(command.Arguments[1])
+		if err != nil {
+			return result, err
+		}
+		result.Slot, err = e.history.slotAt(regNum, at)
+		return result, err
+	case parser.CommandSaveSnapshot:
+		if err := e.saveSnapshot(command.Arguments[0]); err != nil {
+			return result, err
+		}
+		result.Path = command.Arguments[0]
+		return result, nil
+	case parser.CommandLoadSnapshot:
+		snapshot, err := readSnapshotFile(command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		if err := e.restore(snapshot); err != nil {
+			return result, err
+		}
+		result.LotSize = snapshot.Size
+		result.Path = command.Arguments[0]
+		return result, nil
+	case parser.CommandDiffSnapshots:
+		from, err := readSnapshotFile(command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		to, err := readSnapshotFile(command.Arguments[1])
+		if err != nil {
+			return result, err
+		}
+		diff := DiffSnapshots(from, to)
+		result.Diff = &diff
+		return result, nil
+	case parser.CommandWatchlistAdd:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		severity, err := ParseSeverity(command.Arguments[1])
+		if err != nil {
+			return result, err
+		}
+		e.watchlist.add(WatchlistEntry{RegNum: regNum, Severity: severity, Reason: command.Arguments[2]})
+		result.RegNum = regNum
+		return result, nil
+	case parser.CommandWatchlistRemove:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		if err := e.watchlist.remove(regNum); err != nil {
+			return result, err
+		}
+		result.RegNum = regNum
+		return result, nil
+	case parser.CommandWatchlist:
+		result.Watchlist = e.watchlist.list()
+		return result, nil
+	case parser.CommandAlerts:
+		regNum := ""
+		if len(command.Arguments) == 1 {
+			var err error
+			if regNum, err = plate.Normalize(e.plates, command.Arguments[0]); err != nil {
+				return result, err
+			}
+		}
+		result.AlertLog = e.watchlist.alertsFor(regNum)
+		return result, nil
+	case parser.CommandOverstays:
+		result.Overstays = e.overstaying(e.clock())
+		return result, nil
+	case parser.CommandReserve:
+		if e.allocator.GetSize() <= 0 {
+			return result, ErrParkingLotSizeNotSet
+		}
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		from, err := parseTimestamp(command.Arguments[1])
+		if err != nil {
+			return result, err
+		}
+		to, err := parseTimestamp(command.Arguments[2])
+		if err != nil {
+			return result, err
+		}
+		reservation, err := e.reserving.reserve(regNum, from, to, e.allocator.Capacity(dao.SlotCategoryGeneral))
+		if err != nil {
+			return result, err
+		}
+		result.Reservations = []Reservation{reservation}
+		return result, nil
+	case parser.CommandCancelReservation:
+		regNum, err := plate.Normalize(e.plates, command.Arguments[0])
+		if err != nil {
+			return result, err
+		}
+		if err := e.reserving.cancel(regNum); err != nil {
+			return result, err
+		}
+		result.RegNum = regNum
+		return result, nil
+	case parser.CommandReservations:
+		result.Reservations = e.reserving.list()
+		return result, nil
+	case parser.CommandUnknown:
+		return result, parser.ErrUnknownCommand
+	default:
+		return result, ErrUnhandledCommand.WithDetail("command type %d is not handled", command.Type)
+	}
+}
+
+// selectCandidate returns the slot to be allocated to the car with the
+// permit. Cars with a permit get the slots of the permit category first.
+// Once the eligible slots run out, nearest slot of the overflow categories
+// is allocated. Returns 0 if there are no slots for the car.
+func (e *Executor) selectCandidate(permit dao.SlotCategory) int {
+	if permit != "" && permit != dao.SlotCategoryGeneral {
+		if slotID := e.allocator.SelectCandidateIn(permit); slotID != 0 {
+			return slotID
+		}
+	}
+	if slotID := e.allocator.SelectCandidate(); slotID != 0 {
+		return slotID
+	}
+
+	nearest := 0
+	for _, category := range e.overflow {
+		slotID := e.allocator.SelectCandidateIn(category)
+		if slotID != 0 && (nearest == 0 || slotID < nearest) {
+			nearest = slotID
+		}
+	}
+	return nearest
+}
+
+// enqueue adds the car that found the parking lot full to the waitlist.
+// Returns the position of the car in the waitlist, or 0 if the waitlist is
+// disabled or full.
+func (e *Executor) enqueue(car dao.Car) (int, error) {
+	if e.waitlist.Capacity() <= 0 {
+		return 0, nil
+	}
+	if e.storage.SlotNumForCarWithRegNum(car.RegistrationNumber) != 0 {
+		return 0, dao.ErrDuplicateRegNum
+	}
+	if e.waitlist.Position(car.RegistrationNumber) > 0 {
+		return 0, dao.ErrDuplicateRegNum.WithDetail("car %s is already in the waitlist", car.RegistrationNumber)
+	}
+	position, err := e.waitlist.Enqueue(car)
+	if err == ErrWaitlistFull {
+		return 0, nil
+	}
+	return position, err
+}
+
+// admit parks the first waitlisted car eligible for the freed slot. Returns
+// the registration number of the parked car, or "" if no car is eligible.
+func (e *Executor) admit(slotID int) (string, error) {
+	slot, err := e.storage.Slot(slotID)
+	if err != nil || slot.Car != nil || slot.Closed {
+		return "", err
+	}
+	if slot.Category == dao.SlotCategoryGeneral && e.allocator.SelectCandidate() == 0 {
+		// Freed slot is held for the reservations.
+		return "", nil
+	}
+	car, ok := e.waitlist.Next(func(car *dao.Car) bool {
+		if e.storage.SlotNumForCarWithRegNum(car.RegistrationNumber) != 0 {
+			// Car parked since it was waitlisted.
+			return false
+		}
+		if slot.Category == dao.SlotCategoryGeneral || slot.Category == car.Permit {
+			return true
+		}
+		for _, category := range e.overflow {
+			if category == slot.Category {
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		return "", nil
+	}
+	car.ParkedAt = e.clock()
+	if err := e.storage.Park(slotID, &car); err != nil {
+		return "", err
+	}
+	e.allocator.MarkSlotAsAllocated(slotID)
+	e.parked(slotID, &car)
+	return car.RegistrationNumber, nil
+}
+
+// parked records the car parked at the slot.
+func (e *Executor) parked(slotID int, car *dao.Car) {
+	// Waitlisted car parked by hand no longer waits for a slot.
+	if e.waitlist.Position(car.RegistrationNumber) > 0 {
+		_ = e.waitlist.Remove(car.RegistrationNumber)
+	}
+	e.stats.recordPark(slotID, car)
+	e.history.recordPark(slotID, car, car.ParkedAt)
+	if e.events != nil {
+		parked := *car
+		e.events.Publish(events.Event{Type: events.TypeCarParked, At: car.ParkedAt, Slot: slotID, Car: &parked})
+	}
+	if entry, ok := e.watchlist.lookup(car.RegistrationNumber); ok {
+		e.alert(newAlert(entry, car, slotID, car.ParkedAt), car)
+	}
+}
+
+// left records the car left the slot.
+func (e *Executor) left(slotID int, car *dao.Car) {
+	now := e.clock()
+	e.stats.recordLeave(car, now)
+	e.history.recordLeave(slotID, now)
+	if e.events != nil {
+		left := *car
+		e.events.Publish(events.Event{Type: events.TypeCarLeft, At: now, Slot: slotID, Car: &left})
+	}
+}
+
+// moved records the car moved between the slots.
+func (e *Executor) moved(fromSlotID int, toSlotID int, car *dao.Car) {
+	now := e.clock()
+	e.history.recordLeave(fromSlotID, now)
+	e.history.recordPark(toSlotID, car, now)
+	if e.events != nil {
+		moved := *car
+		e.events.Publish(events.Event{Type: events.TypeCarMoved, At: now, Slot: toSlotID, FromSlot: fromSlotID,
+			Car: &moved})
+	}
+}
+
+// publishLotCreated publishes the creation of the parking lot of the size.
+// Lot is not full until the slots are taken.
+func (e *Executor) publishLotCreated(size int) {
+	e.full = false
+	if e.events != nil {
+		e.events.Publish(events.Event{Type: events.TypeLotCreated, At: e.clock(), LotSize: size})
+	}
+}
+
+// publishFull publishes the parking lot getting full or no longer full
+// since the last command.
+func (e *Executor) publishFull() {
+	if e.events == nil || e.allocator.GetSize() <= 0 {
+		return
+	}
+	full := e.selectCandidate("") == 0
+	if full == e.full {
+		return
+	}
+	e.full = full
+	eventType := events.TypeLotNotFull
+	if full {
+		eventType = events.TypeLotFull
+	}
+	e.events.Publish(events.Event{Type: eventType, At: e.clock()})
+}
+
+// newFilter builds the filter of the find command from its options.
+func (e *Executor) newFilter(options map[string]string) (dao.Filter, error) {
+	filter := dao.Filter{}
+	var err error
+	if color, ok := options["colour"]; ok {
+		if filter.Color, err = e.colors.Canonicalize(color); err != nil {
+			return filter, err
+		}
+	}
+	filter.RegNum = e.normalizeRegPattern(options["reg"])
+	if slots, ok := options["slots"]; ok {
+		bounds := strings.SplitN(slots, "-", 2)
+		if filter.FromSlot, err = strconv.Atoi(bounds[0]); err != nil || filter.FromSlot <= 0 {
+			return filter, ErrInvalidFilter.WithDetail("slot range %q must be of the form <from>-<to>", slots)
+		}
+		filter.ToSlot = filter.FromSlot
+		if len(bounds) == 2 {
+			if filter.ToSlot, err = strconv.Atoi(bounds[1]); err != nil || filter.ToSlot < filter.FromSlot {
+				return filter, ErrInvalidFilter.WithDetail("slot range %q must be of the form <from>-<to>", slots)
+			}
+		}
+	}
+	if permit, ok := options["permit"]; ok {
+		if filter.Permit, err = dao.ParseSlotCategory(permit); err != nil {
+			return filter, err
+		}
+	}
+	for name, bound := range map[string]*time.Time{"parked-after": &filter.ParkedAfter, "parked-before": &filter.ParkedBefore} {
+		if value, ok := options[name]; ok {
+			if *bound, err = time.Parse(time.RFC3339, value); err != nil {
+				return filter, ErrInvalidFilter.WithDetail("--%s %q must be an RFC 3339 time (Eg: 2020-01-01T09:00:00Z)", name, value)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// normalizeRegPattern brings the registration number pattern of the find
+// filter to the form the registration numbers are stored in. Pattern that
+// is a whole registration number is normalized by the plate format (Eg:
+// "ka-01 hh 1234" to "KA-01-HH-1234"). Partial prefixes and glob patterns
+// are upper-cased with the spaces replaced by hyphens.
+func (e *Executor) normalizeRegPattern(pattern string) string {
+	pattern = strings.ToUpper(strings.Join(strings.Fields(pattern), "-"))
+	if pattern == "" || strings.ContainsAny(pattern, "*?[\\") {
+		return pattern
+	}
+	if normalized, ok := e.plates.Normalize(pattern); ok {
+		return normalized
+	}
+	return pattern
+}
+
+// newCar validates and canonicalizes the car details of the park commands.
+func (e *Executor) newCar(regNum string, color string, options map[string]string) (dao.Car, error) {
+	car := dao.Car{ParkedAt: e.clock()}
+	var err error
+	if car.RegistrationNumber, err = plate.Normalize(e.plates, regNum); err != nil {
+		return car, err
+	}
+	if car.Color, err = e.colors.Canonicalize(color); err != nil {
+		return car, err
+	}
+	if permit, ok := options["permit"]; ok {
+		if car.Permit, err = dao.ParseSlotCategory(permit); err != nil {
+			return car, err
+		}
+	}
+	return car, nil
+}
+
+// checkEligible checks the car is eligible for the slot chosen by hand.
+// Reserved slots are only for the cars with the permit of the same category.
+func (e *Executor) checkEligible(slotID int, car *dao.Car) error {
+	slot, err := e.storage.Slot(slotID)
+	if err != nil {
+		return err
+	}
+	if slot.Category != dao.SlotCategoryGeneral && slot.Category != car.Permit {
+		return ErrSlotReserved.WithDetail("slot %d is reserved for the cars with %s permit", slotID, slot.Category)
+	}
+	return nil
+}
@@ -0,0 +1,528 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"parking_lot/palette"
+	"parking_lot/parser"
+	"parking_lot/plate"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClock is a clock that advances by the step on each reading.
+type testClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *testClock) Now() time.Time {
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+func newTestClock(step time.Duration) *testClock {
+	return &testClock{now: time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC), step: step}
+}
+
+func newTestExecutor(size int) Executor {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithClock(newTestClock(time.Minute).Now))
+	if size > 0 {
+		_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{strconv.Itoa(size)}))
+	}
+	return executor
+}
+
+func TestExecutor_ExecuteCreateParkingLot(t *testing.T) {
+	executor := newTestExecutor(0)
+	result, err := executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"6"}))
+	if err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	if result.LotSize != 6 {
+		t.Errorf("Execute() got %d want %d", result.LotSize, 6)
+	}
+}
+
+func TestExecutor_ExecuteParkWithoutParkingLot(t *testing.T) {
+	executor := newTestExecutor(0)
+	_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+	if err != ErrParkingLotSizeNotSet {
+		t.Errorf("Execute() Error got %v want %v", err, ErrParkingLotSizeNotSet)
+	}
+}
+
+func TestExecutor_ExecuteParkAndLeave(t *testing.T) {
+	executor := newTestExecutor(2)
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+	if result.Slot != 1 || result.Full {
+		t.Errorf("Execute() got %+v want slot %d", result, 1)
+	}
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1235", "White"}))
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1236", "Red"}))
+	if !result.Full {
+		t.Errorf("Execute() got %+v want full lot", result)
+	}
+
+	result, err := executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	if result.Slot != 1 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 1)
+	}
+}
+
+func TestExecutor_ExecuteQueries(t *testing.T) {
+	executor := newTestExecutor(2)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1235", "White"}))
+
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandRegNumForCarWithColor, []string{"White"}))
+	expectedRegNums := []string{"KA-01-HH-1234", "KA-01-HH-1235"}
+	if !reflect.DeepEqual(result.RegNums, expectedRegNums) {
+		t.Errorf("Execute() got %v want %v", result.RegNums, expectedRegNums)
+	}
+
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithColor, []string{"White"}))
+	expectedSlots := []int{1, 2}
+	if !reflect.DeepEqual(result.Slots, expectedSlots) {
+		t.Errorf("Execute() got %v want %v", result.Slots, expectedSlots)
+	}
+
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNum, []string{"KA-01-HH-1235"}))
+	if result.Slot != 2 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 2)
+	}
+}
+
+func TestExecutor_ExecuteNormalizesRegNum(t *testing.T) {
+	executor := newTestExecutor(3)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+
+	for _, regNum := range []string{"ka-01-hh-1234", "KA01HH1234"} {
+		_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{regNum, "White"}))
+		if err != dao.ErrDuplicateRegNum {
+			t.Errorf("Execute() Error got %v want %v", err, dao.ErrDuplicateRegNum)
+		}
+		result, _ := executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNum, []string{regNum}))
+		if result.Slot != 1 {
+			t.Errorf("Execute() got %d want %d", result.Slot, 1)
+		}
+	}
+}
+
+func TestExecutor_ExecuteRejectsInvalidRegNum(t *testing.T) {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithPlateFormat(plate.Indian{}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"3"}))
+
+	_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"ABC-123", "White"}))
+	if !errors.Is(err, plate.ErrInvalidRegNum) {
+		t.Errorf("Execute() Error got %v want %v", err, plate.ErrInvalidRegNum)
+	}
+}
+
+func TestExecutor_ExecuteChecksArguments(t *testing.T) {
+	executor := newTestExecutor(3)
+	for _, commandType := range []parser.CommandType{parser.CommandPark, parser.CommandParkAt, parser.CommandLeave,
+		parser.CommandMove, parser.CommandWatchlistAdd, parser.CommandReserve} {
+		// Commands built in code without their arguments.
+		_, err := executor.Execute(parser.NewCommand(commandType, nil))
+		if !errors.Is(err, parser.ErrIncorrectUsage) {
+			t.Errorf("Execute(%s) Error got %v want %v", commandType, err, parser.ErrIncorrectUsage)
+		}
+	}
+}
+
+func TestExecutor_ExecuteCanonicalizesColor(t *testing.T) {
+	executor := newTestExecutor(3)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "white"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1235", "Gray"}))
+
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithColor, []string{"WHITE"}))
+	if !reflect.DeepEqual(result.Slots, []int{1}) {
+		t.Errorf("Execute() got %v want %v", result.Slots, []int{1})
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandRegNumForCarWithColor, []string{"grey"}))
+	if !reflect.DeepEqual(result.RegNums, []string{"KA-01-HH-1235"}) {
+		t.Errorf("Execute() got %v want %v", result.RegNums, []string{"KA-01-HH-1235"})
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandStatus, nil))
+	if result.Status[0].Color != "White" || result.Status[1].Color != "Grey" {
+		t.Errorf("Execute() got %+v want canonical colours", result.Status)
+	}
+}
+
+func TestExecutor_ExecuteRejectsUnknownColorInStrictMode(t *testing.T) {
+	colors := palette.Default()
+	colors.SetStrict(true)
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithPalette(colors))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"3"}))
+
+	_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "Light Coral"}))
+	if !errors.Is(err, palette.ErrUnknownColor) {
+		t.Errorf("Execute() Error got %v want %v", err, palette.ErrUnknownColor)
+	}
+}
+
+func parkWithPermit(executor *Executor, regNum string, permit string) Result {
+	command := parser.NewCommand(parser.CommandPark, []string{regNum, "White"})
+	if permit != "" {
+		command.Options = map[string]string{"permit": permit}
+	}
+	result, _ := executor.Execute(command)
+	return result
+}
+
+func TestExecutor_ExecuteParkReservedSlots(t *testing.T) {
+	executor := newTestExecutor(3)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"1", "accessible"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"3", "ev"}))
+
+	if result := parkWithPermit(&executor, "KA-01-HH-0001", ""); result.Slot != 2 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 2)
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0002", ""); !result.Full {
+		t.Errorf("Execute() got %+v want full lot", result)
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0003", "ev"); result.Slot != 3 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 3)
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0004", "ev"); !result.Full {
+		t.Errorf("Execute() got %+v want full lot", result)
+	}
+
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandFreeSlots, []string{}))
+	expected := []FreeSlots{
+		{Category: dao.SlotCategoryGeneral},
+		{Category: dao.SlotCategoryAccessible, Count: 1},
+		{Category: dao.SlotCategoryEV},
+		{Category: dao.SlotCategoryVIP},
+		{Category: dao.SlotCategoryStaff},
+	}
+	if !reflect.DeepEqual(result.FreeSlots, expected) {
+		t.Errorf("Execute() got %+v want %+v", result.FreeSlots, expected)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandFreeSlots, []string{"accessible"}))
+	expected = []FreeSlots{{Category: dao.SlotCategoryAccessible, Count: 1, Slots: []int{1}}}
+	if !reflect.DeepEqual(result.FreeSlots, expected) {
+		t.Errorf("Execute() got %+v want %+v", result.FreeSlots, expected)
+	}
+}
+
+func TestExecutor_ExecuteParkOverflowsIntoReservedSlots(t *testing.T) {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithOverflow(dao.SlotCategoryEV))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"3"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"1", "accessible"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"3", "ev"}))
+
+	for _, expected := range []int{2, 3} {
+		if result := parkWithPermit(&executor, fmt.Sprintf("KA-01-HH-%04d", expected), ""); result.Slot != expected {
+			t.Errorf("Execute() got %d want %d", result.Slot, expected)
+		}
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0009", ""); !result.Full {
+		t.Errorf("Execute() got %+v want full lot", result)
+	}
+}
+
+func TestExecutor_ExecuteParkWithUnknownPermit(t *testing.T) {
+	executor := newTestExecutor(3)
+	command := parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"})
+	command.Options = map[string]string{"permit": "bus"}
+	if _, err := executor.Execute(command); !errors.Is(err, dao.ErrUnknownSlotCategory) {
+		t.Errorf("Execute() Error got %v want %v", err, dao.ErrUnknownSlotCategory)
+	}
+}
+
+func TestExecutor_ExecuteCloseAndOpenSlot(t *testing.T) {
+	executor := newTestExecutor(2)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}))
+
+	_, err := executor.Execute(parser.NewCommand(parser.CommandCloseSlot, []string{"1", "Repainting"}))
+	if err != dao.ErrSlotAlreadyOccupied {
+		t.Errorf("Execute() Error got %v want %v", err, dao.ErrSlotAlreadyOccupied)
+	}
+	result, err := executor.Execute(parser.NewCommand(parser.CommandCloseSlot, []string{"2", "Repainting"}))
+	if err != nil || result.Slot != 2 || result.Reason != "Repainting" {
+		t.Errorf("Execute() got %+v, %v", result, err)
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0002", ""); !result.Full {
+		t.Errorf("Execute() got %+v want full lot", result)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandStatus, nil))
+	expected := "Slot No.    Registration No    Colour\n1           KA-01-HH-0001      White\n2           Closed: Repainting\n"
+	if out := FormatResult(result); out != expected {
+		t.Errorf("FormatResult() got %q want %q", out, expected)
+	}
+
+	if _, err := executor.Execute(parser.NewCommand(parser.CommandOpenSlot, []string{"2"})); err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	if result := parkWithPermit(&executor, "KA-01-HH-0002", ""); result.Slot != 2 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 2)
+	}
+}
+
+func TestExecutor_ExecuteParkAtAndMove(t *testing.T) {
+	executor := newTestExecutor(4)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"4", "vip"}))
+
+	result, err := executor.Execute(parser.NewCommand(parser.CommandParkAt, []string{"3", "KA-01-HH-0003", "White"}))
+	if err != nil || result.Slot != 3 {
+		t.Errorf("Execute() got %+v, %v want slot %d", result, err, 3)
+	}
+	_, err = executor.Execute(parser.NewCommand(parser.CommandParkAt, []string{"4", "KA-01-HH-0004", "White"}))
+	if !errors.Is(err, ErrSlotReserved) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrSlotReserved)
+	}
+	_, err = executor.Execute(parser.NewCommand(parser.CommandParkAt, []string{"3", "KA-01-HH-0004", "White"}))
+	if err != dao.ErrSlotAlreadyOccupied {
+		t.Errorf("Execute() Error got %v want %v", err, dao.ErrSlotAlreadyOccupied)
+	}
+
+	// Allocator skips the slot parked at by hand.
+	for _, expected := range []int{1, 2} {
+		if result := parkWithPermit(&executor, fmt.Sprintf("KA-01-HH-%04d", expected), ""); result.Slot != expected {
+			t.Errorf("Execute() got %d want %d", result.Slot, expected)
+		}
+	}
+
+	_, err = executor.Execute(parser.NewCommand(parser.CommandMove, []string{"1", "4"}))
+	if !errors.Is(err, ErrSlotReserved) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrSlotReserved)
+	}
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"4", "general"}))
+	result, err = executor.Execute(parser.NewCommand(parser.CommandMove, []string{"1", "4"}))
+	if err != nil || result.FromSlot != 1 || result.Slot != 4 {
+		t.Errorf("Execute() got %+v, %v", result, err)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNum, []string{"KA-01-HH-0001"}))
+	if result.Slot != 4 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 4)
+	}
+
+	// Slot moved from is free again.
+	if result := parkWithPermit(&executor, "KA-01-HH-0009", ""); result.Slot != 1 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 1)
+	}
+}
+
+func TestExecutor_ExecuteWaitlist(t *testing.T) {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithWaitlistCapacity(2))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"1"}))
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+
+	for i, regNum := range []string{"KA-01-HH-0002", "KA-01-HH-0003"} {
+		if result := parkWithPermit(&executor, regNum, ""); !result.Full || result.WaitlistPosition != i+1 {
+			t.Errorf("Execute() got %+v want waitlist position %d", result, i+1)
+		}
+	}
+	// Waitlist is full.
+	if result := parkWithPermit(&executor, "KA-01-HH-0004", ""); !result.Full || result.WaitlistPosition != 0 {
+		t.Errorf("Execute() got %+v want full", result)
+	}
+	_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}))
+	if !errors.Is(err, dao.ErrDuplicateRegNum) {
+		t.Errorf("Execute() Error got %v want %v", err, dao.ErrDuplicateRegNum)
+	}
+
+	result, err := executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if err != nil || result.Admitted != "KA-01-HH-0002" {
+		t.Errorf("Execute() got %+v, %v want admitted %s", result, err, "KA-01-HH-0002")
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNum, []string{"KA-01-HH-0002"}))
+	if result.Slot != 1 {
+		t.Errorf("Execute() got %d want %d", result.Slot, 1)
+	}
+
+	_, err = executor.Execute(parser.NewCommand(parser.CommandWaitlistRemove, []string{"KA-01-HH-0003"}))
+	if err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandWaitlist, nil))
+	if len(result.Waitlist) != 0 {
+		t.Errorf("Execute() got %+v want empty waitlist", result.Waitlist)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if result.Admitted != "" {
+		t.Errorf("Execute() got admitted %s want none", result.Admitted)
+	}
+
+	// Disabling the waitlist restores the plain full response.
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandWaitlistCap, []string{"0"}))
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	if result := parkWithPermit(&executor, "KA-01-HH-0005", ""); !result.Full || result.WaitlistPosition != 0 {
+		t.Errorf("Execute() got %+v want full", result)
+	}
+}
+
+func TestExecutor_ExecuteWaitlistedCarParkedByHand(t *testing.T) {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithWaitlistCapacity(2))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"2"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"2", "ev"}))
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	parkWithPermit(&executor, "KA-01-HH-0003", "")
+	if result := parkWithPermit(&executor, "KA-01-HH-0002", "ev"); result.Slot != 2 {
+		t.Fatalf("Execute() got %+v want slot %d", result, 2)
+	}
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandWaitlist, nil))
+	if len(result.Waitlist) != 1 || result.Waitlist[0].RegistrationNumber != "KA-01-HH-0003" {
+		t.Errorf("Execute() got waitlist %+v want KA-01-HH-0003 only", result.Waitlist)
+	}
+
+	result, err := executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if err != nil || result.Admitted != "KA-01-HH-0003" {
+		t.Errorf("Execute() got %+v, %v want admitted %s", result, err, "KA-01-HH-0003")
+	}
+
+	// Car already in the waitlist is not waitlisted twice.
+	parkWithPermit(&executor, "KA-01-HH-0004", "")
+	_, err = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0004", "White"}))
+	if !errors.Is(err, dao.ErrDuplicateRegNum) {
+		t.Errorf("Execute() Error got %v want %v", err, dao.ErrDuplicateRegNum)
+	}
+}
+
+func TestExecutor_ExecuteFind(t *testing.T) {
+	executor := newTestExecutor(4)
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	parkWithPermit(&executor, "KA-02-HH-0003", "")
+
+	find := func(options map[string]string) ([]int, error) {
+		result, err := executor.Execute(parser.Command{Type: parser.CommandFind, Options: options})
+		slots := make([]int, 0)
+		for _, status := range result.Found {
+			slots = append(slots, status.SlotNum)
+		}
+		return slots, err
+	}
+
+	// Test clock parks the cars a minute apart from 09:00.
+	slots, err := find(map[string]string{"colour": "white", "reg": "ka-01-*", "parked-after": "2020-01-01T09:00:00Z"})
+	if err != nil || !reflect.DeepEqual(slots, []int{2}) {
+		t.Errorf("Execute() got %v, %v want %v", slots, err, []int{2})
+	}
+	for _, reg := range []string{"ka-01 hh 0002", "KA01HH0002", "ka 01 hh 000*", "KA-01-HH-0002 "} {
+		slots, err = find(map[string]string{"reg": reg, "slots": "2-3"})
+		if err != nil || !reflect.DeepEqual(slots, []int{2}) {
+			t.Errorf("Execute(--reg=%s) got %v, %v want %v", reg, slots, err, []int{2})
+		}
+	}
+	slots, err = find(map[string]string{"slots": "2-3"})
+	if err != nil || !reflect.DeepEqual(slots, []int{2, 3}) {
+		t.Errorf("Execute() got %v, %v want %v", slots, err, []int{2, 3})
+	}
+	for _, options := range []map[string]string{{"slots": "3-2"}, {"slots": "a"}, {"parked-before": "yesterday"}} {
+		if _, err := find(options); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Execute() Error got %v want %v", err, ErrInvalidFilter)
+		}
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   string
+	}{
+		{name: "create", result: Result{Command: parser.CommandCreateParkingLot, LotSize: 6}, want: "Created a parking lot with 6 slots\n"},
+		{name: "park", result: Result{Command: parser.CommandPark, Slot: 4}, want: "Allocated slot number: 4\n"},
+		{name: "park full", result: Result{Command: parser.CommandPark, Full: true}, want: "Sorry, parking lot is full\n"},
+		{name: "park waitlisted", result: Result{Command: parser.CommandPark, Full: true, WaitlistPosition: 2},
+			want: "Sorry, parking lot is full\nAdded to the waitlist at position: 2\n"},
+		{name: "leave", result: Result{Command: parser.CommandLeave, Slot: 4}, want: "Slot number 4 is free\n"},
+		{name: "leave admits waitlisted", result: Result{Command: parser.CommandLeave, Slot: 4, Admitted: "KA-01-HH-1234"},
+			want: "Slot number 4 is free\nAllocated slot number: 4 to KA-01-HH-1234 from the waitlist\n"},
+		{name: "waitlist", result: Result{Command: parser.CommandWaitlist, Waitlist: []dao.Car{
+			{RegistrationNumber: "KA-01-HH-1234", Color: "White"},
+		}}, want: "Position    Registration No    Colour\n1           KA-01-HH-1234      White\n"},
+		{name: "slots", result: Result{Command: parser.CommandSlotNumForCarWithColor, Slots: []int{1, 2, 4}}, want: "1, 2, 4\n"},
+		{name: "reg nums not found", result: Result{Command: parser.CommandRegNumForCarWithColor}, want: "Not found\n"},
+		{name: "status with categories", result: Result{Command: parser.CommandStatus, Status: []dao.Status{
+			{SlotNum: 1, RegNum: "KA-01-HH-1234", Color: "White", Category: dao.SlotCategoryEV},
+			{SlotNum: 2, Category: dao.SlotCategoryGeneral},
+		}}, want: "Slot No.    Registration No    Category    Colour\n1           KA-01-HH-1234      ev          White\n"},
+		{name: "free slots", result: Result{Command: parser.CommandFreeSlots, FreeSlots: []FreeSlots{
+			{Category: dao.SlotCategoryGeneral, Count: 2},
+			{Category: dao.SlotCategoryEV},
+		}}, want: "Category    Free slots\ngeneral     2\nev          0\n"},
+		{name: "free slots of category", result: Result{Command: parser.CommandFreeSlots, FreeSlots: []FreeSlots{
+			{Category: dao.SlotCategoryGeneral, Count: 2, Slots: []int{2, 3}},
+		}}, want: "2, 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatResult(tt.result); got != tt.want {
+				t.Errorf("FormatResult() got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutor_Events(t *testing.T) {
+	bus := events.NewBus()
+	got := make([]string, 0)
+	bus.Subscribe(func(event events.Event) {
+		description := event.Type.String()
+		if event.Car != nil {
+			description += fmt.Sprintf(" %s %d", event.Car.RegistrationNumber, event.Slot)
+		}
+		got = append(got, description)
+	})
+	allocator := NewNearestAllocator()
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &dao.InMemoryStorage{}, WithWaitlistCapacity(1), WithEvents(bus))
+
+	commands := []parser.Command{
+		parser.NewCommand(parser.CommandCreateParkingLot, []string{"2"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0002", "White"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0003", "White"}),
+		parser.NewCommand(parser.CommandLeave, []string{"1"}),
+		parser.NewCommand(parser.CommandLeave, []string{"2"}),
+		parser.NewCommand(parser.CommandMove, []string{"1", "2"}),
+		parser.NewCommand(parser.CommandLeave, []string{"2"}),
+	}
+	for _, command := range commands {
+		if _, err := executor.Execute(command); err != nil {
+			t.Fatalf("Execute(%s) got %v", command.Type, err)
+		}
+	}
+
+	want := []string{
+		"lot_created",
+		"car_parked KA-01-HH-0001 1",
+		"car_parked KA-01-HH-0002 2",
+		"lot_full",
+		// Waitlisted car is parked in the freed slot at once.
+		"car_left KA-01-HH-0001 1",
+		"car_parked KA-01-HH-0003 1",
+		"car_left KA-01-HH-0002 2",
+		"lot_not_full",
+		"car_moved KA-01-HH-0003 2",
+		"car_left KA-01-HH-0003 2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Execute() published %v want %v", got, want)
+	}
+}
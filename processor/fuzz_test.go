@@ -0,0 +1,117 @@
+//go:build go1.18
+// +build go1.18
+
+package processor
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// maxFuzzLotSize bounds the size of the parking lots created by the fuzzed
+// inputs, so that a single input does not take seconds to run.
+const maxFuzzLotSize = 1024
+
+// fuzzable tells whether every parking lot created by the input is small
+// enough to be fuzzed, and that the input does not write to the file system.
+func fuzzable(input []byte) bool {
+	tokenizer := parser.NewTokenizer(bytes.NewReader(input))
+	for {
+		command, err := parser.NextCommand(&tokenizer)
+		if err == io.EOF || (err != nil && common.CategoryOf(err) == common.CategoryInternal) {
+			return true
+		}
+		if err == nil && writesFiles[command.Type] {
+			return false
+		}
+		if err == nil && command.Type == parser.CommandCreateParkingLot {
+			size, err := strconv.ParseInt(command.Arguments[0], 10, 64)
+			if err == nil && size > maxFuzzLotSize {
+				return false
+			}
+		}
+	}
+}
+
+// checkInvariants checks every slot is either free, occupied or closed,
+// and that the allocator and the storage agree on it.
+func checkInvariants(t *testing.T, allocator Allocator, storage dao.Storage) {
+	size := allocator.GetSize()
+	free := make(map[int]bool)
+	for _, category := range dao.SlotCategories {
+		for _, slotID := range allocator.FreeSlots(category) {
+			if free[slotID] || slotID <= 0 || slotID > size {
+				t.Fatalf("slot %d is free more than once or is out of bounds", slotID)
+			}
+			free[slotID] = true
+		}
+	}
+
+	stored := make(map[int]bool)
+	regNums := make(map[string]bool)
+	for _, entry := range storage.Slots() {
+		if stored[entry.SlotNum] || entry.SlotNum <= 0 || entry.SlotNum > size {
+			t.Fatalf("slot %d is stored more than once or is out of bounds", entry.SlotNum)
+		}
+		stored[entry.SlotNum] = true
+		occupied := entry.RegNum != ""
+		if free[entry.SlotNum] == (occupied || entry.Closed) {
+			t.Fatalf("slot %d: free %v, occupied %v, closed %v", entry.SlotNum, free[entry.SlotNum], occupied, entry.Closed)
+		}
+		if !occupied {
+			continue
+		}
+		if regNums[entry.RegNum] {
+			t.Fatalf("registration number %s is parked more than once", entry.RegNum)
+		}
+		regNums[entry.RegNum] = true
+		if slotID := storage.SlotNumForCarWithRegNum(entry.RegNum); slotID != entry.SlotNum {
+			t.Fatalf("SlotNumForCarWithRegNum(%s) got %d want %d", entry.RegNum, slotID, entry.SlotNum)
+		}
+	}
+	// Slots not stored are general and free.
+	for slotID := 1; slotID <= size; slotID++ {
+		if !stored[slotID] && !free[slotID] {
+			t.Fatalf("slot %d is neither stored nor free", slotID)
+		}
+	}
+}
+
+func FuzzProcess(f *testing.F) {
+	fixture, err := ioutil.ReadFile("../functional_spec/fixtures/file_input.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(fixture)
+	f.Add([]byte("create_parking_lot 3\nset_slot_category 2 ev\nclose_slot 3 Repainting\n" +
+		"park KA-01-HH-1234 White --permit=ev\npark_at 1 KA-01-HH-1235 Red\nmove 2 1\nleave 0\nleave -1\nopen_slot 3\n"))
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if !fuzzable(input) {
+			t.Skip()
+		}
+		allocator := NewNearestAllocator()
+		storage := dao.InMemoryStorage{}
+		mutex := sync.Mutex{}
+		executor := NewExecutor(&mutex, &allocator, &storage)
+		tokenizer := parser.NewTokenizer(bytes.NewReader(input))
+		for {
+			_, err := executor.Process(&tokenizer)
+			if err == io.EOF {
+				break
+			} else if err == ErrUnhandledCommand {
+				t.Fatalf("Process() Error %v", err)
+			} else if err != nil && common.CategoryOf(err) == common.CategoryInternal {
+				// Failure reading the input (Eg: line too long).
+				break
+			}
+		}
+		checkInvariants(t, &allocator, &storage)
+	})
+}
@@ -0,0 +1,291 @@
+package processor
+
+import (
+	"parking_lot/common"
+	"parking_lot/dao"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrInvalidTimestamp specifies the time of the point-in-time queries
+	// that can not be parsed.
+	ErrInvalidTimestamp = common.NewError("ERR_INVALID_TIMESTAMP", common.CategoryValidation,
+		"timestamp must be an RFC 3339 time (Eg: 2020-01-01T09:00:00Z)")
+	// ErrHistoryUnavailable specifies the time of the point-in-time queries
+	// the history is no longer kept for.
+	ErrHistoryUnavailable = common.NewError("ERR_HISTORY_UNAVAILABLE", common.CategoryNotFound,
+		"history is not kept for the time")
+	// ErrHistoryRetentionInvalid specifies the negative history retention.
+	ErrHistoryRetentionInvalid = common.NewError("ERR_HISTORY_RETENTION_INVALID", common.CategoryValidation,
+		"history retention must not be negative")
+)
+
+// DefaultHistoryRetention is how long the history of the point-in-time
+// queries is kept for.
+const DefaultHistoryRetention = 30 * 24 * time.Hour
+
+const (
+	// minCheckpointInterval is the least number of events between the
+	// checkpoints of the history.
+	minCheckpointInterval = 256
+	// checkpointSpacing is the number of events per occupied slot between
+	// the checkpoints of the history.
+	checkpointSpacing = 4
+)
+
+// HistoryEvent is a car parked at or leaving the slot, as stored in the
+// snapshots.
+type HistoryEvent struct {
+	At   time.Time `json:"at"`
+	Slot int       `json:"slot"`
+	// Car is the car parked at the slot. Nil when the slot is freed.
+	Car *dao.Car `json:"car,omitempty"`
+}
+
+// historyEvent is a car parked at or leaving the slot.
+type historyEvent struct {
+	at   time.Time
+	slot int
+	// car is the car parked at the slot. Zero when the slot is freed.
+	car dao.Car
+}
+
+// historyCheckpoint is the occupancy after the first events of the history.
+// Occupied slots are mapped to the index of the event the car was parked at.
+type historyCheckpoint struct {
+	events   int
+	occupied map[int]int
+}
+
+// history records the park and leave events the past occupancy of the
+// parking lot is rebuilt from. Events are kept in the clock order, an event
+// read earlier than the previous one is recorded at the time of the
+// previous one.
+//
+// A checkpoint of the occupancy is taken once the events since the previous
+// checkpoint outnumber the cars occupying the slots at the time of the
+// previous checkpoint checkpointSpacing times. Queries replay the events
+// following the nearest checkpoint only, which costs about as much as copying
+// the occupancy of the checkpoint, while the checkpoints take a fraction of
+// the memory of the events.
+//
+// Events older than the retention are dropped in batches, once they make up
+// half of the history. Cars still parked at the time the history is cut at
+// keep the events they were parked with.
+type history struct {
+	events      []historyEvent
+	checkpoints []historyCheckpoint
+	// retention is how long the events are kept for. Zero keeps them all.
+	retention time.Duration
+	// since is the time the history is cut at. Zero if none of the events
+	// were dropped.
+	since time.Time
+	// kept is the number of the events at or before the cut, kept for the
+	// cars parked at the time.
+	kept int
+}
+
+func newHistory() *history {
+	return &history{retention: DefaultHistoryRetention}
+}
+
+// WithHistoryRetention sets how long the history of the point-in-time
+// queries is kept for. Defaults to DefaultHistoryRetention. Zero keeps the
+// whole history.
+func WithHistoryRetention(retention time.Duration) Option {
+	return func(e *Executor) {
+		e.history.retention = retention
+	}
+}
+
+// recordPark records the car parked at the slot at the time.
+func (h *history) recordPark(slotID int, car *dao.Car, at time.Time) {
+	h.record(historyEvent{at: at, slot: slotID, car: *car})
+}
+
+// recordLeave records the slot freed at the time.
+func (h *history) recordLeave(slotID int, at time.Time) {
+	h.record(historyEvent{at: at, slot: slotID})
+}
+
+func (h *history) record(event historyEvent) {
+	if n := len(h.events); n > 0 && event.at.Before(h.events[n-1].at) {
+		event.at = h.events[n-1].at
+	}
+	h.events = append(h.events, event)
+	h.checkpoint(len(h.events))
+	if h.retention > 0 {
+		h.expire(event.at.Add(-h.retention))
+	}
+}
+
+// checkpoint takes a checkpoint after the given number of events if enough
+// events followed the previous one.
+func (h *history) checkpoint(events int) {
+	last := historyCheckpoint{}
+	if n := len(h.checkpoints); n > 0 {
+		last = h.checkpoints[n-1]
+	}
+	since := events - last.events
+	if since >= minCheckpointInterval && since > checkpointSpacing*len(last.occupied) {
+		h.checkpoints = append(h.checkpoints, historyCheckpoint{
+			events:   events,
+			occupied: h.replay(last, events),
+		})
+	}
+}
+
+// expire cuts the history at the horizon once the events at or before it
+// make up half of the events following the previous cut.
+func (h *history) expire(horizon time.Time) {
+	cut := h.count(horizon)
+	// Events kept at the previous cut are not counted as expired.
+	expired := cut - h.kept
+	if expired < 2*minCheckpointInterval || 2*expired < len(h.events)-h.kept {
+		return
+	}
+	occupied := h.occupiedAt(horizon)
+	parked := make([]int, 0, len(occupied))
+	for _, index := range occupied {
+		parked = append(parked, index)
+	}
+	sort.Ints(parked)
+	events := make([]historyEvent, 0, len(parked)+len(h.events)-cut)
+	for _, index := range parked {
+		events = append(events, h.events[index])
+	}
+	h.load(append(events, h.events[cut:]...), horizon)
+}
+
+// load replaces the history with the events cut at the time.
+func (h *history) load(events []historyEvent, since time.Time) {
+	h.events = events
+	h.since = since
+	h.kept = 0
+	if !since.IsZero() {
+		h.kept = h.count(since)
+	}
+	h.checkpoints = nil
+	for i := 1; i <= len(h.events); i++ {
+		h.checkpoint(i)
+	}
+}
+
+// check returns ErrHistoryUnavailable if the history is cut after the time.
+func (h *history) check(at time.Time) error {
+	if at.Before(h.since) {
+		return ErrHistoryUnavailable.WithDetail("history before %s is no longer kept", h.since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// export returns the events in the form stored in the snapshots.
+func (h *history) export() []HistoryEvent {
+	events := make([]HistoryEvent, 0, len(h.events))
+	for i := range h.events {
+		event := HistoryEvent{At: h.events[i].at, Slot: h.events[i].slot}
+		if h.events[i].car.RegistrationNumber != "" {
+			car := h.events[i].car
+			event.Car = &car
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// restore replaces the history with the events of the snapshot, validated
+// by the caller.
+func (h *history) restore(events []HistoryEvent, since time.Time) {
+	restored := make([]historyEvent, 0, len(events))
+	for _, event := range events {
+		restoredEvent := historyEvent{at: event.At, slot: event.Slot}
+		if event.Car != nil {
+			restoredEvent.car = *event.Car
+		}
+		restored = append(restored, restoredEvent)
+	}
+	h.load(restored, since)
+}
+
+// count returns the number of the events that happened at or before the
+// time.
+func (h *history) count(at time.Time) int {
+	return sort.Search(len(h.events), func(i int) bool {
+		return h.events[i].at.After(at)
+	})
+}
+
+// occupiedAt rebuilds the occupancy of the slots at the time. Occupied slots
+// are mapped to the index of the event the car was parked at.
+func (h *history) occupiedAt(at time.Time) map[int]int {
+	events := h.count(at)
+	// Number of the checkpoints taken within those events.
+	checkpoints := sort.Search(len(h.checkpoints), func(i int) bool {
+		return h.checkpoints[i].events > events
+	})
+	from := historyCheckpoint{}
+	if checkpoints > 0 {
+		from = h.checkpoints[checkpoints-1]
+	}
+	return h.replay(from, events)
+}
+
+// replay applies the events following the checkpoint up to the given
+// number of events to a copy of its occupancy.
+func (h *history) replay(from historyCheckpoint, events int) map[int]int {
+	occupied := make(map[int]int, len(from.occupied))
+	for slotID, index := range from.occupied {
+		occupied[slotID] = index
+	}
+	for index := from.events; index < events; index++ {
+		if event := &h.events[index]; event.car.RegistrationNumber == "" {
+			delete(occupied, event.slot)
+		} else {
+			occupied[event.slot] = index
+		}
+	}
+	return occupied
+}
+
+// statusAt returns the rows of the slots occupied at the time ordered by
+// the slot. Categories and closures of the slots are not recorded. Returns
+// ErrHistoryUnavailable if the history is cut after the time.
+func (h *history) statusAt(at time.Time) ([]dao.Status, error) {
+	if err := h.check(at); err != nil {
+		return nil, err
+	}
+	occupied := h.occupiedAt(at)
+	status := make([]dao.Status, 0, len(occupied))
+	for slotID, index := range occupied {
+		car := &h.events[index].car
+		status = append(status, dao.Status{SlotNum: slotID, RegNum: car.RegistrationNumber, Color: car.Color,
+			Permit: car.Permit, ParkedAt: car.ParkedAt})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].SlotNum < status[j].SlotNum })
+	return status, nil
+}
+
+// slotAt returns the slot the car was parked at at the time, or 0 if the
+// car was not parked. Returns ErrHistoryUnavailable if the history is cut
+// after the time.
+func (h *history) slotAt(regNum string, at time.Time) (int, error) {
+	if err := h.check(at); err != nil {
+		return 0, err
+	}
+	for slotID, index := range h.occupiedAt(at) {
+		if h.events[index].car.RegistrationNumber == regNum {
+			return slotID, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseTimestamp parses the time argument of the point-in-time queries.
+func parseTimestamp(value string) (time.Time, error) {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return at, ErrInvalidTimestamp.WithDetail("%q must be an RFC 3339 time (Eg: 2020-01-01T09:00:00Z)", value)
+	}
+	return at, nil
+}
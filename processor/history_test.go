@@ -0,0 +1,207 @@
+package processor
+
+import (
+	"errors"
+	"math/rand"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExecutor_ExecuteStatusAt(t *testing.T) {
+	executor := newTestExecutor(3)
+	// Test clock reads 09:00, 09:01, ... in the order of the commands.
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandMove, []string{"2", "3"}))
+	parkWithPermit(&executor, "KA-01-HH-0003", "")
+
+	tests := []struct {
+		at    string
+		slots []int
+		regs  []string
+	}{
+		{"2020-01-01T08:59:59Z", []int{}, []string{}},
+		{"2020-01-01T09:00:30Z", []int{1}, []string{"KA-01-HH-0001"}},
+		{"2020-01-01T09:01:00Z", []int{1, 2}, []string{"KA-01-HH-0001", "KA-01-HH-0002"}},
+		{"2020-01-01T09:02:00Z", []int{2}, []string{"KA-01-HH-0002"}},
+		{"2020-01-01T14:33:00+05:30", []int{3}, []string{"KA-01-HH-0002"}},
+		{"2020-01-02T00:00:00Z", []int{1, 3}, []string{"KA-01-HH-0003", "KA-01-HH-0002"}},
+	}
+	for _, test := range tests {
+		result, err := executor.Execute(parser.NewCommand(parser.CommandStatusAt, []string{test.at}))
+		if err != nil {
+			t.Fatalf("Execute(status_at %s) got %v", test.at, err)
+		}
+		slots, regs := make([]int, 0), make([]string, 0)
+		for _, entry := range result.Status {
+			slots = append(slots, entry.SlotNum)
+			regs = append(regs, entry.RegNum)
+		}
+		if !reflect.DeepEqual(slots, test.slots) || !reflect.DeepEqual(regs, test.regs) {
+			t.Errorf("Execute(status_at %s) got %v %v want %v %v", test.at, slots, regs, test.slots, test.regs)
+		}
+	}
+
+	_, err := executor.Execute(parser.NewCommand(parser.CommandStatusAt, []string{"yesterday"}))
+	if !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("Execute(status_at yesterday) got %v want %v", err, ErrInvalidTimestamp)
+	}
+}
+
+func TestExecutor_ExecuteSlotNumForCarWithRegNumAt(t *testing.T) {
+	executor := newTestExecutor(3)
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandMove, []string{"2", "3"}))
+
+	tests := []struct {
+		regNum string
+		at     string
+		want   int
+	}{
+		{"KA-01-HH-0001", "2020-01-01T09:01:00Z", 1},
+		{"KA-01-HH-0001", "2020-01-01T09:02:00Z", 0},
+		{"ka-01-hh-0002", "2020-01-01T09:02:59Z", 2},
+		{"KA-01-HH-0002", "2020-01-01T09:03:00Z", 3},
+		{"KA-01-HH-9999", "2020-01-01T09:03:00Z", 0},
+	}
+	for _, test := range tests {
+		result, err := executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNumAt, []string{test.regNum, test.at}))
+		if err != nil {
+			t.Fatalf("Execute(slot_number_for_registration_number_at %s %s) got %v", test.regNum, test.at, err)
+		}
+		if result.Slot != test.want {
+			t.Errorf("Execute(slot_number_for_registration_number_at %s %s) got %d want %d", test.regNum, test.at, result.Slot, test.want)
+		}
+	}
+}
+
+func TestExecutor_RestoreHistory(t *testing.T) {
+	parkedAt := time.Date(2019, time.December, 31, 18, 0, 0, 0, time.UTC)
+	executor := newTestExecutor(0)
+	err := executor.Restore(Snapshot{Size: 2, Slots: []dao.Slot{
+		{ID: 1, Car: &dao.Car{RegistrationNumber: "KA-01-HH-0001", Color: "White"}},
+		{ID: 2, Car: &dao.Car{RegistrationNumber: "KA-01-HH-0002", Color: "White", ParkedAt: parkedAt}},
+	}})
+	if err != nil {
+		t.Fatalf("Restore() got %v", err)
+	}
+
+	// Car without the parking time is recorded as parked at the restore.
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandStatusAt, []string{"2019-12-31T20:00:00Z"}))
+	if len(result.Status) != 1 || result.Status[0].SlotNum != 2 {
+		t.Errorf("Execute(status_at) got %v want slot 2 only", result.Status)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandStatusAt, []string{"2020-01-01T09:00:00Z"}))
+	if len(result.Status) != 2 {
+		t.Errorf("Execute(status_at) got %v want both slots", result.Status)
+	}
+}
+
+func TestHistory_Checkpoints(t *testing.T) {
+	start := time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)
+	random := rand.New(rand.NewSource(1))
+	h := newHistory()
+	occupied := make(map[int]bool)
+	for i := 0; i < 20000; i++ {
+		slotID := random.Intn(500) + 1
+		at := start.Add(time.Duration(i) * time.Second)
+		if occupied[slotID] {
+			h.recordLeave(slotID, at)
+		} else {
+			h.recordPark(slotID, &dao.Car{RegistrationNumber: "KA-01-HH-" + strconv.Itoa(i), Color: "White"}, at)
+		}
+		occupied[slotID] = !occupied[slotID]
+	}
+
+	if len(h.checkpoints) == 0 {
+		t.Fatalf("history has no checkpoints")
+	}
+	checkpointed := 0
+	for i, checkpoint := range h.checkpoints {
+		checkpointed += len(checkpoint.occupied)
+		if i > 0 && checkpoint.events-h.checkpoints[i-1].events > minCheckpointInterval+checkpointSpacing*len(h.checkpoints[i-1].occupied)+1 {
+			t.Errorf("checkpoint %d taken %d events after the previous one", i, checkpoint.events-h.checkpoints[i-1].events)
+		}
+	}
+	if checkpointed > len(h.events) {
+		t.Errorf("checkpoints hold %d cars for %d events", checkpointed, len(h.events))
+	}
+
+	for i := 0; i < 100; i++ {
+		at := start.Add(time.Duration(random.Intn(21000)-500) * time.Second)
+		got := h.occupiedAt(at)
+		// Replay of all the events from the beginning.
+		events := 0
+		for events < len(h.events) && !h.events[events].at.After(at) {
+			events++
+		}
+		if want := h.replay(historyCheckpoint{}, events); !reflect.DeepEqual(got, want) {
+			t.Fatalf("occupiedAt(%v) got %d cars want %d", at, len(got), len(want))
+		}
+	}
+}
+
+func TestHistory_RecordsInClockOrder(t *testing.T) {
+	start := time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)
+	h := newHistory()
+	h.recordPark(1, &dao.Car{RegistrationNumber: "KA-01-HH-0001", Color: "White"}, start)
+	// Clock stepped back. Leave is recorded at the time of the park.
+	h.recordLeave(1, start.Add(-time.Hour))
+	if slotID, _ := h.slotAt("KA-01-HH-0001", start.Add(-time.Minute)); slotID != 0 {
+		t.Errorf("slotAt() got %d want %d", slotID, 0)
+	}
+	if slotID, _ := h.slotAt("KA-01-HH-0001", start); slotID != 0 {
+		t.Errorf("slotAt() got %d want %d", slotID, 0)
+	}
+}
+
+func TestHistory_Retention(t *testing.T) {
+	start := time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)
+	random := rand.New(rand.NewSource(1))
+	h := newHistory()
+	h.retention = time.Hour
+	full := newHistory()
+	full.retention = 0
+	occupied := make(map[int]bool)
+	for i := 0; i < 20000; i++ {
+		slotID := random.Intn(100) + 1
+		at := start.Add(time.Duration(i) * time.Second)
+		if occupied[slotID] {
+			h.recordLeave(slotID, at)
+			full.recordLeave(slotID, at)
+		} else {
+			car := &dao.Car{RegistrationNumber: "KA-01-HH-" + strconv.Itoa(i), Color: "White"}
+			h.recordPark(slotID, car, at)
+			full.recordPark(slotID, car, at)
+		}
+		occupied[slotID] = !occupied[slotID]
+	}
+
+	end := start.Add(20000 * time.Second)
+	if h.since.Before(end.Add(-3*time.Hour)) || h.since.After(end.Add(-time.Hour)) {
+		t.Errorf("history cut at %v want within 3 hours before %v", h.since, end)
+	}
+	if len(h.events) > 2*3600+100 {
+		t.Errorf("history keeps %d events want at most %d", len(h.events), 2*3600+100)
+	}
+	if _, err := h.statusAt(h.since.Add(-time.Second)); !errors.Is(err, ErrHistoryUnavailable) {
+		t.Errorf("statusAt() before the cut got %v want %v", err, ErrHistoryUnavailable)
+	}
+	for i := 0; i < 100; i++ {
+		at := h.since.Add(time.Duration(random.Int63n(int64(end.Sub(h.since)))))
+		got, err := h.statusAt(at)
+		if err != nil {
+			t.Fatalf("statusAt(%v) got %v", at, err)
+		}
+		if want, _ := full.statusAt(at); !reflect.DeepEqual(got, want) {
+			t.Fatalf("statusAt(%v) got %d cars want %d", at, len(got), len(want))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrStayLimitInvalid specifies stay limit that can not be parsed.
+var ErrStayLimitInvalid = common.NewError("ERR_STAY_LIMIT_INVALID", common.CategoryValidation,
+	"stay limit is not valid")
+
+// StayLimits are the maximum times the cars may stay parked. Limit of the
+// category of the slot takes precedence over the limit of the lot. Zero
+// means no limit.
+type StayLimits struct {
+	Lot        time.Duration
+	Categories map[dao.SlotCategory]time.Duration
+}
+
+// ParseStayLimits parses the comma separated list of the limits. Limits
+// without a category apply to the lot (Eg: "8h,general=4h,ev=90m").
+func ParseStayLimits(spec string) (StayLimits, error) {
+	limits := StayLimits{Categories: make(map[dao.SlotCategory]time.Duration)}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		limit, err := time.ParseDuration(parts[len(parts)-1])
+		if err != nil || limit < 0 {
+			return limits, ErrStayLimitInvalid.WithDetail("stay limit %q must be a duration (Eg: 4h, 90m)", entry)
+		}
+		if len(parts) == 1 {
+			limits.Lot = limit
+			continue
+		}
+		category, err := dao.ParseSlotCategory(parts[0])
+		if err != nil {
+			return limits, err
+		}
+		limits.Categories[category] = limit
+	}
+	return limits, nil
+}
+
+// limit returns the limit of the slot category.
+func (l StayLimits) limit(category dao.SlotCategory) time.Duration {
+	if limit, ok := l.Categories[category]; ok && limit > 0 {
+		return limit
+	}
+	return l.Lot
+}
+
+// Overstay is a car parked past the stay limit of its slot.
+type Overstay struct {
+	Slot     int              `json:"slot"`
+	RegNum   string           `json:"registration_number"`
+	Color    string           `json:"colour"`
+	Category dao.SlotCategory `json:"category"`
+	ParkedAt time.Time        `json:"parked_at"`
+	// Limit is the stay limit of the slot and Over is the time the car
+	// stayed past it, in seconds.
+	Limit float64 `json:"limit_seconds"`
+	Over  float64 `json:"over_seconds"`
+}
+
+// overstayTracker holds the stay limits and the stays already reported by
+// CheckOverstays.
+type overstayTracker struct {
+	limits StayLimits
+	// reported maps the registration number to the parking time of the
+	// stay reported.
+	reported map[string]time.Time
+}
+
+// WithStayLimits sets the stay limits the overstays are detected with. By
+// default, there are no limits.
+func WithStayLimits(limits StayLimits) Option {
+	return func(e *Executor) {
+		e.overstays.limits = limits
+	}
+}
+
+// overstaying returns the cars parked past their limit at the time, the
+// furthest over first. Cars without the parking time are left out.
+func (e *Executor) overstaying(now time.Time) []Overstay {
+	found := make([]Overstay, 0)
+	for _, entry := range e.storage.Slots() {
+		if entry.RegNum == "" || entry.ParkedAt.IsZero() {
+			continue
+		}
+		limit := e.overstays.limits.limit(entry.Category)
+		if over := now.Sub(entry.ParkedAt) - limit; limit > 0 && over > 0 {
+			found = append(found, Overstay{Slot: entry.SlotNum, RegNum: entry.RegNum, Color: entry.Color,
+				Category: entry.Category, ParkedAt: entry.ParkedAt, Limit: limit.Seconds(), Over: over.Seconds()})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Over != found[j].Over {
+			return found[i].Over > found[j].Over
+		}
+		return found[i].Slot < found[j].Slot
+	})
+	return found
+}
+
+// CheckOverstays publishes the overstay event of each car gone past its limit
+// since the last check. Each stay is reported once. Returns the newly
+// reported overstays.
+func (e *Executor) CheckOverstays() []Overstay {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	now := e.clock()
+	found := e.overstaying(now)
+	reported := make(map[string]time.Time, len(found))
+	fresh := make([]Overstay, 0)
+	for _, overstay := range found {
+		reported[overstay.RegNum] = overstay.ParkedAt
+		if parkedAt, ok := e.overstays.reported[overstay.RegNum]; ok && parkedAt.Equal(overstay.ParkedAt) {
+			continue
+		}
+		fresh = append(fresh, overstay)
+		if e.events != nil {
+			e.events.Publish(events.Event{Type: events.TypeOverstay, At: now, Slot: overstay.Slot,
+				Car:   &dao.Car{RegistrationNumber: overstay.RegNum, Color: overstay.Color, ParkedAt: overstay.ParkedAt},
+				Limit: overstay.Limit, Over: overstay.Over})
+		}
+	}
+	// Stays that ended are forgotten, so the map only holds the current
+	// overstays.
+	e.overstays.reported = reported
+	return fresh
+}
+
+// WatchOverstays checks for the overstays on every tick until stop is
+// closed. Meant to be run on a goroutine of its own (Eg: with the channel of
+// a time.Ticker).
+func (e *Executor) WatchOverstays(ticks <-chan time.Time, stop <-chan struct{}) {
+	for {
+		select {
+		case <-ticks:
+			e.CheckOverstays()
+		case <-stop:
+			return
+		}
+	}
+}
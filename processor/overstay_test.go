@@ -0,0 +1,126 @@
+package processor
+
+import (
+	"errors"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"parking_lot/parser"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseStayLimits(t *testing.T) {
+	limits, err := ParseStayLimits("8h, general=4h,ev=90m")
+	if err != nil {
+		t.Fatalf("ParseStayLimits() Error %v", err)
+	}
+	want := StayLimits{Lot: 8 * time.Hour,
+		Categories: map[dao.SlotCategory]time.Duration{dao.SlotCategoryGeneral: 4 * time.Hour, dao.SlotCategoryEV: 90 * time.Minute}}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("ParseStayLimits() got %+v want %+v", limits, want)
+	}
+	if limits.limit(dao.SlotCategoryVIP) != 8*time.Hour || limits.limit(dao.SlotCategoryEV) != 90*time.Minute {
+		t.Errorf("limit() got %v, %v", limits.limit(dao.SlotCategoryVIP), limits.limit(dao.SlotCategoryEV))
+	}
+
+	if _, err := ParseStayLimits("4 hours"); !errors.Is(err, ErrStayLimitInvalid) {
+		t.Errorf("ParseStayLimits() Error got %v want %v", err, ErrStayLimitInvalid)
+	}
+	if _, err := ParseStayLimits("visitor=4h"); !errors.Is(err, dao.ErrUnknownSlotCategory) {
+		t.Errorf("ParseStayLimits() Error got %v want %v", err, dao.ErrUnknownSlotCategory)
+	}
+}
+
+// newOverstayExecutor builds the executor of a 3 slot lot with the stay
+// limits. The clock only moves when the test moves it.
+func newOverstayExecutor(clock *testClock, bus *events.Bus) Executor {
+	allocator := NewNearestAllocator()
+	limits := StayLimits{Lot: 4 * time.Hour, Categories: map[dao.SlotCategory]time.Duration{dao.SlotCategoryEV: time.Hour}}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &dao.InMemoryStorage{}, WithClock(clock.Now),
+		WithStayLimits(limits), WithEvents(bus))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"3"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"3", "ev"}))
+	return executor
+}
+
+func TestExecutor_Overstays(t *testing.T) {
+	clock := newTestClock(0)
+	executor := newOverstayExecutor(clock, nil)
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	clock.now = clock.now.Add(time.Hour)
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	parkWithPermit(&executor, "KA-01-HH-0003", "ev")
+
+	clock.now = clock.now.Add(3 * time.Hour)
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandOverstays, nil))
+	if len(result.Overstays) != 1 || result.Overstays[0].RegNum != "KA-01-HH-0003" {
+		t.Errorf("Execute() got %+v want KA-01-HH-0003 only", result.Overstays)
+	}
+
+	clock.now = clock.now.Add(2 * time.Hour)
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandOverstays, nil))
+	got := make([]string, 0)
+	for _, overstay := range result.Overstays {
+		got = append(got, overstay.RegNum)
+	}
+	if want := []string{"KA-01-HH-0003", "KA-01-HH-0001", "KA-01-HH-0002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Execute() got %v want %v", got, want)
+	}
+	want := "Slot No.    Registration No    Parked at                 Limit       Over by\n" +
+		"3           KA-01-HH-0003      2020-01-01T10:00:00Z      1h0m0s      4h0m0s\n" +
+		"1           KA-01-HH-0001      2020-01-01T09:00:00Z      4h0m0s      2h0m0s\n" +
+		"2           KA-01-HH-0002      2020-01-01T10:00:00Z      4h0m0s      1h0m0s\n"
+	if out := FormatResult(result); out != want {
+		t.Errorf("FormatResult() got %q want %q", out, want)
+	}
+}
+
+func TestExecutor_CheckOverstays(t *testing.T) {
+	clock := newTestClock(0)
+	bus := events.NewBus()
+	published := make(chan events.Event, 10)
+	bus.Subscribe(func(event events.Event) { published <- event }, events.TypeOverstay)
+	executor := newOverstayExecutor(clock, bus)
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	parkWithPermit(&executor, "KA-01-HH-0002", "ev")
+
+	ticks, stop := make(chan time.Time), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		executor.WatchOverstays(ticks, stop)
+		close(done)
+	}()
+	// Tick returns once the check of the first of the two ticks is over.
+	// Clock is read by the checks while the executor is locked.
+	tick := func(after time.Duration) {
+		executor.mutex.Lock()
+		clock.now = clock.now.Add(after)
+		executor.mutex.Unlock()
+		ticks <- time.Time{}
+		ticks <- time.Time{}
+	}
+
+	tick(2 * time.Hour)
+	tick(time.Hour)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"3"}))
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	tick(3 * time.Hour)
+	tick(5 * time.Hour)
+	close(stop)
+	<-done
+	close(published)
+
+	got := make([]string, 0)
+	for event := range published {
+		got = append(got, event.Car.RegistrationNumber)
+		if event.Over <= 0 || event.Limit <= 0 {
+			t.Errorf("WatchOverstays() published %+v", event)
+		}
+	}
+	// Second stay of KA-01-HH-0002 is reported again.
+	if want := []string{"KA-01-HH-0002", "KA-01-HH-0001", "KA-01-HH-0002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WatchOverstays() published overstays of %v want %v", got, want)
+	}
+}
@@ -1,113 +1,66 @@
 package processor
 
 import (
-	"errors"
-	"fmt"
+	"parking_lot/common"
 	"parking_lot/dao"
 	"parking_lot/parser"
-	"strconv"
-	"strings"
 	"sync"
 )
 
 var (
 	// ErrParkingLotSizeInvalid specifies <= 0 size parking lot
-	ErrParkingLotSizeInvalid = errors.New("ERR_PARKING_LOT_SIZE_INVALID")
+	ErrParkingLotSizeInvalid = common.NewError("ERR_PARKING_LOT_SIZE_INVALID", common.CategoryValidation,
+		"size of the parking lot must be greater than zero")
 	// ErrParkingLotSizeAlreadySet specifies size of parking lot is already set.
-	ErrParkingLotSizeAlreadySet = errors.New("ERR_PARKING_LOT_SIZE_ALREADY_SET")
+	ErrParkingLotSizeAlreadySet = common.NewError("ERR_PARKING_LOT_SIZE_ALREADY_SET", common.CategoryValidation,
+		"parking lot is already created")
 	// ErrParkingLotSizeNotSet specifies size of parking lot is not set.
-	ErrParkingLotSizeNotSet = errors.New("ERR_PARKING_LOT_SIZE_NOT_SET")
+	ErrParkingLotSizeNotSet = common.NewError("ERR_PARKING_LOT_SIZE_NOT_SET", common.CategoryValidation,
+		"parking lot is not created yet")
 	// ErrInvalidSlotID specifies slot ID is either not valid or is out of parking lot bounds.
-	ErrInvalidSlotID = errors.New("ERR_INVALID_SLOT_ID")
+	ErrInvalidSlotID = common.NewError("ERR_INVALID_SLOT_ID", common.CategoryValidation,
+		"slot number is not a valid number")
+	// ErrSlotReserved specifies reserved slot requested for a car without the matching permit.
+	ErrSlotReserved = common.NewError("ERR_SLOT_RESERVED", common.CategoryValidation,
+		"slot is reserved for the cars with a permit")
+	// ErrInvalidFilter specifies find filter that is not valid.
+	ErrInvalidFilter = common.NewError("ERR_INVALID_FILTER", common.CategoryValidation,
+		"filter is not valid")
+	// ErrUnhandledCommand specifies a command type the processor does not know how to execute.
+	ErrUnhandledCommand = common.NewError("ERR_UNHANDLED_COMMAND", common.CategoryInternal,
+		"command type is not handled")
 )
 
+// Process reads the next command from the tokenizer, executes it and
+// returns the textual output of the command.
+//
+// Deprecated: Process builds a new Executor for each command, so the state
+// kept by the Executor (Eg: statistics, waitlist, history, watchlist and
+// reservations) is lost between the commands. Use Executor.Process instead.
 func Process(tokenizer *parser.Tokenizer, mutex *sync.Mutex, allocator Allocator, s dao.Storage) (string, error) {
 	command, err := parser.NextCommand(tokenizer)
-
 	if err != nil {
 		return "", err
 	}
 
-	// Use mutex. Though not necessarily required in this particular example
-	// as stdin I suppose writing to stdin wouldn't be concurrent (due to interleaving)
-	// Adding it here as in real-world, a parking lot might have multiple entry and exit
-	// point which may lead to concurrent access.
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	// IMPORTANT:
-	// In the switch statement below, number of arguments is already
-	// validated by parser. Argument slice indices can be safely
-	// used without additional bound checks.
-	switch command.Type {
-	case parser.CommandCreateParkingLot:
-		size, err := strconv.ParseInt(command.Arguments[0], 10, 64)
-		if err != nil {
-			return "", ErrInvalidSlotID
-		} else if size <= 0 {
-			return "", ErrParkingLotSizeInvalid
-		} else if allocator.GetSize() > 0 {
-			return "", ErrParkingLotSizeAlreadySet
-		}
+	executor := NewExecutor(mutex, allocator, s)
+	result, err := executor.Execute(command)
+	if err != nil {
+		return "", err
+	}
+	return FormatResult(result), nil
+}
 
-		allocator.SetSize(int(size))
-		s.SetSize(int(size))
-		return fmt.Sprintf("Created a parking lot with %d slots\n", size), nil
-	case parser.CommandPark:
-		if allocator.GetSize() <= 0 {
-			return "", ErrParkingLotSizeNotSet
-		}
-		car := dao.Car{
-			RegistrationNumber: command.Arguments[0],
-			Color:              command.Arguments[1],
-		}
-		slotID := allocator.SelectCandidate()
-		if slotID == 0 {
-			return "Sorry, parking lot is full\n", nil
-		}
-		err = s.Park(slotID, &car)
-		if err != nil {
-			return "", err
-		}
-		allocator.MarkAsAllocated()
-		return fmt.Sprintf("Allocated slot number: %d\n", slotID), nil
-	case parser.CommandLeave:
-		if allocator.GetSize() <= 0 {
-			return "", ErrParkingLotSizeNotSet
-		}
-		slotID, err := strconv.ParseInt(command.Arguments[0], 10, 64)
-		if err != nil {
-			return "", ErrInvalidSlotID
-		}
-		_, err = s.Leave(int(slotID))
-		if err != nil {
-			return "", err
-		}
-		allocator.MarkAsAvailable(int(slotID))
-		return fmt.Sprintf("Slot number %d is free\n", slotID), nil
-	case parser.CommandStatus:
-		return Format(s.Status()), nil
-	case parser.CommandRegNumForCarWithColor:
-		regNums := s.RegNumForCarsWithColor(command.Arguments[0])
-		if len(regNums) <= 0 {
-			return fmt.Sprint("Not found\n"), nil
-		}
-		return fmt.Sprintf("%s\n", strings.Join(regNums, ", ")), nil
-	case parser.CommandSlotNumForCarWithColor:
-		slots := s.SlotNumForCarsWithColor(command.Arguments[0])
-		if len(slots) <= 0 {
-			return fmt.Sprint("Not found\n"), nil
-		}
-		return fmt.Sprintf("%s\n", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(slots)), ", "), "[]")), nil
-	case parser.CommandSlotNumForCarWithRegNum:
-		slotID := s.SlotNumForCarWithRegNum(command.Arguments[0])
-		if slotID == 0 {
-			return fmt.Sprint("Not found\n"), nil
-		}
-		return fmt.Sprintf("%d\n", slotID), nil
-	case parser.CommandUnknown:
-		return "", parser.ErrUnknownCommand
-	default:
-		panic(fmt.Sprintf("Unhandled command type %v", command.Type))
+// Process reads the next command from the tokenizer, executes it and
+// returns the textual output of the command.
+func (e *Executor) Process(tokenizer *parser.Tokenizer) (string, error) {
+	command, err := parser.NextCommand(tokenizer)
+	if err != nil {
+		return "", err
+	}
+	result, err := e.Execute(command)
+	if err != nil {
+		return "", err
 	}
+	return FormatResult(result), nil
 }
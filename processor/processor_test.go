@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"sync"
+	"testing"
+)
+
+// benchmarkColors are the colours of the cars in the benchmark workloads.
+var benchmarkColors = []string{"White", "Black", "Red", "Blue", "Silver", "Grey"}
+
+// benchmarkWorkload generates the input of the number of lines for the lot
+// of the size. Cars park and leave at random, with one in ten lines being a
+// query. Same arguments always generate the same input.
+func benchmarkWorkload(lines int, size int) []byte {
+	random := rand.New(rand.NewSource(1))
+	allocator := NewNearestAllocator()
+	allocator.SetSize(size)
+	occupied := make([]int, 0, size)
+	regNums := make(map[int]string, size)
+
+	buffer := bytes.Buffer{}
+	fmt.Fprintf(&buffer, "create_parking_lot %d\n", size)
+	for i := 1; i < lines; i++ {
+		switch n := random.Intn(10); {
+		case n == 0 && len(occupied) > 0:
+			slotID := occupied[random.Intn(len(occupied))]
+			fmt.Fprintf(&buffer, "slot_number_for_registration_number %s\n", regNums[slotID])
+		case n == 0:
+			fmt.Fprintf(&buffer, "slot_numbers_for_cars_with_colour %s\n", benchmarkColors[random.Intn(len(benchmarkColors))])
+		case n <= 5 && len(occupied) < size:
+			slotID := allocator.SelectCandidate()
+			allocator.MarkAsAllocated()
+			occupied = append(occupied, slotID)
+			regNums[slotID] = fmt.Sprintf("KA-%02d-HH-%04d", i/10000%100, i%10000)
+			fmt.Fprintf(&buffer, "park %s %s\n", regNums[slotID], benchmarkColors[random.Intn(len(benchmarkColors))])
+		case len(occupied) > 0:
+			j := random.Intn(len(occupied))
+			slotID := occupied[j]
+			occupied[j] = occupied[len(occupied)-1]
+			occupied = occupied[:len(occupied)-1]
+			allocator.MarkAsAvailable(slotID)
+			fmt.Fprintf(&buffer, "leave %d\n", slotID)
+		default:
+			fmt.Fprintf(&buffer, "status\n")
+		}
+	}
+	return buffer.Bytes()
+}
+
+var (
+	millionLinesOnce sync.Once
+	millionLines     []byte
+)
+
+// BenchmarkProcess runs a million lines through Executor.Process for each
+// iteration.
+func BenchmarkProcess(b *testing.B) {
+	millionLinesOnce.Do(func() {
+		millionLines = benchmarkWorkload(1000000, 10000)
+	})
+	b.SetBytes(int64(len(millionLines)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		allocator := NewNearestAllocator()
+		storage := dao.InMemoryStorage{}
+		mutex := sync.Mutex{}
+		executor := NewExecutor(&mutex, &allocator, &storage)
+		tokenizer := parser.NewTokenizer(bytes.NewReader(millionLines))
+		for {
+			_, err := executor.Process(&tokenizer)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package processor
+
+import (
+	"parking_lot/common"
+	"parking_lot/dao"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrReservationInvalid specifies reservation window that can not be
+	// reserved (Eg: ending before it starts or in the past).
+	ErrReservationInvalid = common.NewError("ERR_RESERVATION_INVALID", common.CategoryValidation,
+		"reservation window is not valid")
+	// ErrReservationUnavailable specifies reservation window without the
+	// capacity left.
+	ErrReservationUnavailable = common.NewError("ERR_RESERVATION_UNAVAILABLE", common.CategoryCapacity,
+		"no capacity left for the reservation window")
+	// ErrSlotHeld specifies free general slot chosen by hand while all the
+	// free general slots are held for the reservations.
+	ErrSlotHeld = common.NewError("ERR_SLOT_HELD", common.CategoryCapacity,
+		"free general slots are held for the reservations")
+	// ErrNotReserved specifies car without a reservation.
+	ErrNotReserved = common.NewError("ERR_NOT_RESERVED", common.CategoryNotFound,
+		"car does not have a reservation")
+)
+
+// DefaultReservationGrace is the time a reservation holds the capacity for
+// the car after the start of the window.
+const DefaultReservationGrace = 15 * time.Minute
+
+// Reservation guarantees a general slot to the car arriving in the window.
+// The slot is chosen on arrival.
+type Reservation struct {
+	RegNum string    `json:"registration_number"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	// HoldUntil is the time the capacity is released if the car does not
+	// arrive, the end of the grace period or of the window.
+	HoldUntil time.Time `json:"hold_until"`
+}
+
+// active returns true if the reservation holds the capacity at the time.
+func (r Reservation) active(at time.Time) bool {
+	return !at.Before(r.From) && at.Before(r.HoldUntil)
+}
+
+// WithReservationGrace sets the time a reservation holds the capacity after
+// the start of the window before it is released as a no-show. Defaults to
+// DefaultReservationGrace. Zero holds the capacity for the whole window.
+func WithReservationGrace(grace time.Duration) Option {
+	return func(e *Executor) {
+		e.reserving.grace = grace
+	}
+}
+
+// reservingAllocator is the Allocator holding back the free general slots
+// for the reservations. Walk-ins get a general slot only while the free
+// general slots outnumber the reservations holding the capacity. The car
+// arriving with a reservation is claimed first, which releases its hold.
+type reservingAllocator struct {
+	Allocator
+	clock        Clock
+	grace        time.Duration
+	reservations []Reservation
+}
+
+// SelectCandidate returns 0 if the free general slots are all held.
+func (r *reservingAllocator) SelectCandidate() int {
+	if len(r.reservations) == 0 {
+		// Clock is not read unless there are reservations.
+		return r.Allocator.SelectCandidate()
+	}
+	if !r.available(r.clock()) {
+		return 0
+	}
+	return r.Allocator.SelectCandidate()
+}
+
+// available returns false if the free general slots at the time are all
+// held for the reservations. The free slots are counted by the allocator.
+func (r *reservingAllocator) available(at time.Time) bool {
+	if len(r.reservations) == 0 {
+		return true
+	}
+	holds := r.holds(at)
+	return holds == 0 || r.Allocator.FreeCount(dao.SlotCategoryGeneral) > holds
+}
+
+// holds returns the number of the reservations holding the capacity at the
+// time. Released reservations are dropped.
+func (r *reservingAllocator) holds(at time.Time) int {
+	r.release(at)
+	holds := 0
+	for _, reservation := range r.reservations {
+		if reservation.active(at) {
+			holds++
+		}
+	}
+	return holds
+}
+
+// release drops the reservations of the no-shows past the grace period and
+// the ones past their window.
+func (r *reservingAllocator) release(at time.Time) {
+	kept := r.reservations[:0]
+	for _, reservation := range r.reservations {
+		if at.Before(reservation.HoldUntil) {
+			kept = append(kept, reservation)
+		}
+	}
+	r.reservations = kept
+}
+
+// reserve adds the reservation of the car for the window if the capacity
+// allows. Capacity is the number of the general slots not closed.
+func (r *reservingAllocator) reserve(regNum string, from time.Time, to time.Time, capacity int) (Reservation, error) {
+	now := r.clock()
+	if !from.Before(to) || !now.Before(to) {
+		return Reservation{}, ErrReservationInvalid.WithDetail("reservation from %s to %s must end after it starts and in the future",
+			from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	r.release(now)
+	if i := r.find(regNum); i >= 0 {
+		return Reservation{}, ErrReservationInvalid.WithDetail("car %s already has a reservation from %s", regNum,
+			r.reservations[i].From.Format(time.RFC3339))
+	}
+	if r.peak(from, to)+1 > capacity {
+		return Reservation{}, ErrReservationUnavailable.WithDetail("all %d general slots are reserved at some point from %s to %s",
+			capacity, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	reservation := Reservation{RegNum: regNum, From: from, To: to, HoldUntil: to}
+	if r.grace > 0 && from.Add(r.grace).Before(to) {
+		reservation.HoldUntil = from.Add(r.grace)
+	}
+	r.reservations = append(r.reservations, reservation)
+	sort.SliceStable(r.reservations, func(i, j int) bool { return r.reservations[i].From.Before(r.reservations[j].From) })
+	return reservation, nil
+}
+
+// peak returns the highest number of the reservations overlapping at once
+// within the window. The reserved cars are counted for their whole window,
+// as they are expected to stay parked until its end.
+func (r *reservingAllocator) peak(from time.Time, to time.Time) int {
+	type change struct {
+		at    time.Time
+		delta int
+	}
+	changes := make([]change, 0)
+	for _, reservation := range r.reservations {
+		if reservation.From.Before(to) && from.Before(reservation.To) {
+			changes = append(changes, change{reservation.From, 1}, change{reservation.To, -1})
+		}
+	}
+	// Windows ending at the time do not overlap the ones starting at it.
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].at.Equal(changes[j].at) {
+			return changes[i].at.Before(changes[j].at)
+		}
+		return changes[i].delta < changes[j].delta
+	})
+	peak, current := 0, 0
+	for _, c := range changes {
+		if current += c.delta; current > peak {
+			peak = current
+		}
+	}
+	return peak
+}
+
+// cancel drops the reservation of the car.
+func (r *reservingAllocator) cancel(regNum string) error {
+	i := r.find(regNum)
+	if i < 0 {
+		return ErrNotReserved.WithDetail("car %s does not have a reservation", regNum)
+	}
+	r.reservations = append(r.reservations[:i], r.reservations[i+1:]...)
+	return nil
+}
+
+// claim drops the reservation of the car arriving at the time, releasing
+// its hold. Car arriving before its window only gives up the reservation, as
+// there is no capacity held for it yet. Returns false if the car has no
+// reservation.
+func (r *reservingAllocator) claim(regNum string, at time.Time) (Reservation, bool) {
+	if len(r.reservations) == 0 {
+		return Reservation{}, false
+	}
+	r.release(at)
+	i := r.find(regNum)
+	if i < 0 {
+		return Reservation{}, false
+	}
+	reservation := r.reservations[i]
+	r.reservations = append(r.reservations[:i], r.reservations[i+1:]...)
+	return reservation, true
+}
+
+// unclaim puts back the claimed reservation of the car that did not get a
+// slot after all.
+func (r *reservingAllocator) unclaim(reservation Reservation) {
+	r.reservations = append(r.reservations, reservation)
+	sort.SliceStable(r.reservations, func(i, j int) bool { return r.reservations[i].From.Before(r.reservations[j].From) })
+}
+
+func (r *reservingAllocator) find(regNum string) int {
+	for i, reservation := range r.reservations {
+		if reservation.RegNum == regNum {
+			return i
+		}
+	}
+	return -1
+}
+
+// list returns the reservations not yet released in the order of their
+// windows.
+func (r *reservingAllocator) list() []Reservation {
+	if len(r.reservations) == 0 {
+		return []Reservation{}
+	}
+	r.release(r.clock())
+	return append([]Reservation{}, r.reservations...)
+}
+
+// isFreeGeneral tells whether the slot is one of the free general slots.
+func (e *Executor) isFreeGeneral(slotID int) bool {
+	slot, err := e.storage.Slot(slotID)
+	return err == nil && slot.Category == dao.SlotCategoryGeneral && slot.Car == nil && !slot.Closed
+}
+
+// checkHeld checks the free general slot chosen by hand (Eg: by park_at) may
+// be taken at the time. Returns ErrSlotHeld while the free general slots are
+// all held for the reservations.
+func (e *Executor) checkHeld(slotID int, at time.Time) error {
+	if !e.reserving.available(at) {
+		return ErrSlotHeld.WithDetail("slot %d can not be taken, the free general slots are held for the reservations",
+			slotID)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package processor
+
+import (
+	"errors"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newReservationExecutor builds the executor of the lot of the size with the
+// waitlist of a single car. The clock only moves when the test moves it.
+func newReservationExecutor(clock *testClock, size string) Executor {
+	allocator := NewNearestAllocator()
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &dao.InMemoryStorage{}, WithClock(clock.Now),
+		WithWaitlistCapacity(1))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{size}))
+	return executor
+}
+
+func newAllocator() Allocator {
+	allocator := NewNearestAllocator()
+	return &allocator
+}
+
+func reserve(executor *Executor, regNum string, from string, to string) error {
+	_, err := executor.Execute(parser.NewCommand(parser.CommandReserve, []string{regNum, from, to}))
+	return err
+}
+
+func TestExecutor_Reserve(t *testing.T) {
+	executor := newReservationExecutor(newTestClock(0), "3")
+	for _, args := range [][]string{
+		{"KA-01-HH-0001", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z"},
+		{"KA-01-HH-0002", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z"},
+		{"KA-01-HH-0003", "2020-01-01T11:00:00Z", "2020-01-01T13:00:00Z"},
+		{"KA-01-HH-0004", "2020-01-01T12:00:00Z", "2020-01-01T13:00:00Z"},
+	} {
+		if err := reserve(&executor, args[0], args[1], args[2]); err != nil {
+			t.Fatalf("Execute(reserve %v) Error %v", args, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{name: "All slots reserved", args: []string{"KA-01-HH-0005", "2020-01-01T11:30:00Z", "2020-01-01T12:30:00Z"},
+			wantErr: ErrReservationUnavailable},
+		{name: "Ends before it starts", args: []string{"KA-01-HH-0005", "2020-01-01T14:00:00Z", "2020-01-01T13:00:00Z"},
+			wantErr: ErrReservationInvalid},
+		{name: "Ends in the past", args: []string{"KA-01-HH-0005", "2020-01-01T07:00:00Z", "2020-01-01T08:00:00Z"},
+			wantErr: ErrReservationInvalid},
+		{name: "Already reserved", args: []string{"KA-01-HH-0001", "2020-01-01T14:00:00Z", "2020-01-01T15:00:00Z"},
+			wantErr: ErrReservationInvalid},
+		{name: "Invalid time", args: []string{"KA-01-HH-0005", "10:00", "2020-01-01T15:00:00Z"},
+			wantErr: ErrInvalidTimestamp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reserve(&executor, tt.args[0], tt.args[1], tt.args[2]); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Execute() Error got %v want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := executor.Execute(parser.NewCommand(parser.CommandCancelReservation, []string{"KA-01-HH-0004"})); err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	_, err := executor.Execute(parser.NewCommand(parser.CommandCancelReservation, []string{"KA-01-HH-0004"}))
+	if !errors.Is(err, ErrNotReserved) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrNotReserved)
+	}
+	empty := newTestExecutor(0)
+	if err := reserve(&empty, "KA-01-HH-0001", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z"); err != ErrParkingLotSizeNotSet {
+		t.Errorf("Execute() Error got %v want %v", err, ErrParkingLotSizeNotSet)
+	}
+}
+
+func TestExecutor_ReservationHolds(t *testing.T) {
+	clock := newTestClock(0)
+	executor := newReservationExecutor(clock, "3")
+	_ = reserve(&executor, "KA-01-HH-0001", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z")
+	_ = reserve(&executor, "KA-01-HH-0002", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z")
+	_ = reserve(&executor, "KA-01-HH-0003", "2020-01-01T11:00:00Z", "2020-01-01T13:00:00Z")
+	if result := parkWithPermit(&executor, "KA-01-HH-1000", ""); result.Slot != 1 {
+		t.Errorf("Execute() got slot %d want %d before the holds", result.Slot, 1)
+	}
+
+	// Both free slots are held for the reservations starting at 10:00.
+	clock.now = clock.now.Add(time.Hour)
+	if result := parkWithPermit(&executor, "KA-01-HH-1001", ""); !result.Full || result.WaitlistPosition != 1 {
+		t.Errorf("Execute() got %+v want the walk-in waitlisted", result)
+	}
+	result := parkWithPermit(&executor, "KA-01-HH-0001", "")
+	if result.Slot != 2 || !result.Reserved {
+		t.Errorf("Execute() got %+v want slot %d reserved", result, 2)
+	}
+
+	// No-show releases the hold after the grace period.
+	clock.now = clock.now.Add(20 * time.Minute)
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandReservations, nil))
+	want := "Registration No    From                      To                        Held until\n" +
+		"KA-01-HH-0003      2020-01-01T11:00:00Z      2020-01-01T13:00:00Z      2020-01-01T11:15:00Z\n"
+	if got := FormatResult(result); got != want {
+		t.Errorf("FormatResult() got %q want %q", got, want)
+	}
+	if result = parkWithPermit(&executor, "KA-01-HH-1002", ""); result.Slot != 3 {
+		t.Errorf("Execute() got %+v want slot %d after the release", result, 3)
+	}
+
+	// Slot freed while the held capacity is taken is not given to the
+	// waitlisted walk-in.
+	clock.now = clock.now.Add(40 * time.Minute)
+	if result, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"})); result.Admitted != "" {
+		t.Errorf("Execute() admitted %s while the slots are held", result.Admitted)
+	}
+	if result = parkWithPermit(&executor, "KA-01-HH-1003", ""); !result.Full {
+		t.Errorf("Execute() got slot %d want the walk-in refused", result.Slot)
+	}
+	if result = parkWithPermit(&executor, "KA-01-HH-0003", ""); result.Slot != 1 || !result.Reserved {
+		t.Errorf("Execute() got %+v want slot %d reserved", result, 1)
+	}
+	if result, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"2"})); result.Admitted != "KA-01-HH-1001" {
+		t.Errorf("Execute() admitted %q want %q", result.Admitted, "KA-01-HH-1001")
+	}
+}
+
+func TestSnapshot_Reservations(t *testing.T) {
+	clock := newTestClock(0)
+	executor := newReservationExecutor(clock, "2")
+	_ = reserve(&executor, "KA-01-HH-0001", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z")
+	snapshot := executor.Snapshot()
+
+	restored := NewExecutor(&sync.Mutex{}, newAllocator(), &dao.InMemoryStorage{}, WithClock(clock.Now))
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() Error %v", err)
+	}
+	result, _ := restored.Execute(parser.NewCommand(parser.CommandReservations, nil))
+	if !reflect.DeepEqual(result.Reservations, snapshot.Reservations) || len(result.Reservations) != 1 {
+		t.Errorf("Execute() got %+v want %+v", result.Reservations, snapshot.Reservations)
+	}
+
+	snapshot.Reservations[0].To = snapshot.Reservations[0].From
+	invalid := NewExecutor(&sync.Mutex{}, newAllocator(), &dao.InMemoryStorage{}, WithClock(clock.Now))
+	if err := invalid.Restore(snapshot); !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("Restore() Error got %v want %v", err, ErrSnapshotInvalid)
+	}
+}
+
+func TestExecutor_ReservationHoldsSlotsChosenByHand(t *testing.T) {
+	clock := newTestClock(0)
+	executor := newReservationExecutor(clock, "3")
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"3", "ev"}))
+	_ = reserve(&executor, "KA-01-HH-0001", "2020-01-01T10:00:00Z", "2020-01-01T12:00:00Z")
+	if result := parkWithPermit(&executor, "KA-01-HH-1000", "ev"); result.Slot != 3 {
+		t.Fatalf("Execute() got %+v want slot %d", result, 3)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if result := parkWithPermit(&executor, "KA-01-HH-1001", ""); result.Slot != 1 {
+		t.Fatalf("Execute() got %+v want slot %d", result, 1)
+	}
+
+	// Last free general slot is held for KA-01-HH-0001.
+	parkAt := parser.Command{Type: parser.CommandParkAt, Arguments: []string{"2", "KA-01-HH-1002", "White"}}
+	if _, err := executor.Execute(parkAt); !errors.Is(err, ErrSlotHeld) {
+		t.Errorf("Execute(park_at) Error got %v want %v", err, ErrSlotHeld)
+	}
+	move := parser.NewCommand(parser.CommandMove, []string{"3", "2"})
+	if _, err := executor.Execute(move); !errors.Is(err, ErrSlotHeld) {
+		t.Errorf("Execute(move) Error got %v want %v", err, ErrSlotHeld)
+	}
+	parkAt.Arguments = []string{"2", "KA-01-HH-0001", "White"}
+	if result, err := executor.Execute(parkAt); err != nil || result.Slot != 2 || !result.Reserved {
+		t.Errorf("Execute(park_at) got %+v, %v want slot %d reserved", result, err, 2)
+	}
+	if reservations := executor.Snapshot().Reservations; len(reservations) != 0 {
+		t.Errorf("Snapshot() got reservations %+v want none", reservations)
+	}
+}
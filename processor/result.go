@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"parking_lot/dao"
+	"parking_lot/parser"
+)
+
+// Result is the structured outcome of a single executed command. Only the
+// fields relevant to the executed command type are populated.
+type Result struct {
+	// Command is the type of the command that produced this result.
+	Command parser.CommandType `json:"command"`
+	// LotSize is the number of slots created by create_parking_lot or
+	// loaded by load_snapshot.
+	LotSize int `json:"lot_size,omitempty"`
+	// Slot is the slot allocated by park and park_at, freed by leave, moved
+	// to by move or found by slot_number_for_registration_number and
+	// slot_number_for_registration_number_at. Zero when there is no such slot.
+	Slot int `json:"slot,omitempty"`
+	// FromSlot is the slot the car is moved from by move.
+	FromSlot int `json:"from_slot,omitempty"`
+	// Reserved is set when park parked the car with a reservation, which is
+	// used up.
+	Reserved bool `json:"reserved,omitempty"`
+	// Full is set when park could not find a free slot.
+	Full bool `json:"full,omitempty"`
+	// WaitlistPosition is the position in the waitlist of the car that
+	// found the parking lot full. Zero when the car is not waitlisted.
+	WaitlistPosition int `json:"waitlist_position,omitempty"`
+	// Admitted is the registration number of the waitlisted car parked in
+	// the slot freed by leave, open_slot or move.
+	Admitted string `json:"admitted,omitempty"`
+	// Status contains the rows returned by status and status_at.
+	Status []dao.Status `json:"status,omitempty"`
+	// RegNums contains the hits of registration_numbers_for_cars_with_colour.
+	RegNums []string `json:"registration_numbers,omitempty"`
+	// Slots contains the hits of slot_numbers_for_cars_with_colour.
+	Slots []int `json:"slots,omitempty"`
+	// Category is the category set by set_slot_category.
+	Category dao.SlotCategory `json:"category,omitempty"`
+	// Reason is the reason the slot is closed by close_slot.
+	Reason string `json:"reason,omitempty"`
+	// FreeSlots contains the free slots of each category returned by free_slots.
+	FreeSlots []FreeSlots `json:"free_slots,omitempty"`
+	// Waitlist contains the cars returned by waitlist in the arrival order.
+	Waitlist []dao.Car `json:"waitlist,omitempty"`
+	// RegNum is the registration number removed by waitlist_remove, added
+	// or removed by watchlist_add and watchlist_remove, or the one whose
+	// reservation is cancelled by cancel_reservation.
+	RegNum string `json:"registration_number,omitempty"`
+	// WaitlistCapacity is the capacity set by waitlist_cap.
+	WaitlistCapacity int `json:"waitlist_capacity,omitempty"`
+	// Found contains the rows matching the filters of find.
+	Found []dao.Status `json:"found,omitempty"`
+	// Stats is the report returned by stats.
+	Stats *Stats `json:"stats,omitempty"`
+	// Path is the file the statistics are exported to by export_stats, or
+	// the snapshot is saved to by save_snapshot or loaded from by
+	// load_snapshot.
+	Path string `json:"path,omitempty"`
+	// Diff is the difference of the snapshots compared by diff_snapshots.
+	Diff *SnapshotDiff `json:"diff,omitempty"`
+	// Watchlist contains the entries returned by watchlist.
+	Watchlist []WatchlistEntry `json:"watchlist,omitempty"`
+	// AlertLog contains the logged alerts returned by alerts.
+	AlertLog []Alert `json:"alert_log,omitempty"`
+	// Overstays contains the cars past their stay limit returned by
+	// overstays, the furthest over first.
+	Overstays []Overstay `json:"overstays,omitempty"`
+	// Reservations contains the reservation made by reserve, or the ones
+	// returned by reservations in the order of their windows.
+	Reservations []Reservation `json:"reservations,omitempty"`
+	// Alerts contains the alerts raised by the command (Eg: park of a
+	// watchlisted car). Set for any command.
+	Alerts []Alert `json:"alerts,omitempty"`
+}
+
+// FreeSlots counts the free slots of a category. Slots lists them only if
+// free_slots is given the category.
+type FreeSlots struct {
+	Category dao.SlotCategory `json:"category"`
+	Count    int              `json:"count"`
+	Slots    []int            `json:"slots,omitempty"`
+}
@@ -0,0 +1,335 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownFormat specifies output format that is not supported.
+var ErrUnknownFormat = common.NewError("ERR_UNKNOWN_FORMAT", common.CategoryUsage,
+	"output format must be one of text, json")
+
+// Formatter formats results and errors of the commands for the output.
+type Formatter interface {
+	Format(result Result) string
+	FormatError(err error) string
+}
+
+// TextFormatter formats results as the human-readable text printed by the CLI.
+type TextFormatter struct{}
+
+func (TextFormatter) Format(result Result) string {
+	return FormatResult(result)
+}
+
+func (TextFormatter) FormatError(err error) string {
+	return err.Error() + "\n"
+}
+
+// JSONFormatter formats each result as a single line JSON object.
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(result Result) string {
+	out, err := json.Marshal(result)
+	if err != nil {
+		// Result contains only plain values. Marshalling can not fail.
+		panic(err)
+	}
+	return string(out) + "\n"
+}
+
+// jsonError is the JSON representation of an error.
+type jsonError struct {
+	Code     string `json:"code"`
+	Category string `json:"category"`
+	Detail   string `json:"detail"`
+}
+
+func (JSONFormatter) FormatError(err error) string {
+	out, _ := json.Marshal(struct {
+		Error jsonError `json:"error"`
+	}{Error: jsonError{Code: common.CodeOf(err), Category: common.CategoryOf(err).String(), Detail: common.DetailOf(err)}})
+	return string(out) + "\n"
+}
+
+// NewFormatter returns the formatter for the output format name.
+func NewFormatter(name string) (Formatter, error) {
+	switch name {
+	case "text":
+		return TextFormatter{}, nil
+	case "json":
+		return JSONFormatter{}, nil
+	default:
+		return nil, ErrUnknownFormat.WithDetail("unknown output format %q, must be one of text, json", name)
+	}
+}
+
+// FormatResult formats the result of a command into the text printed by the
+// CLI. Alerts raised by the command follow its output.
+func FormatResult(result Result) string {
+	return formatCommand(result) + formatAlerts(result.Alerts)
+}
+
+// formatCommand formats the outcome of the command.
+func formatCommand(result Result) string {
+	switch result.Command {
+	case parser.CommandCreateParkingLot:
+		return fmt.Sprintf("Created a parking lot with %d slots\n", result.LotSize)
+	case parser.CommandPark:
+		if result.Full && result.WaitlistPosition > 0 {
+			return fmt.Sprintf("Sorry, parking lot is full\nAdded to the waitlist at position: %d\n", result.WaitlistPosition)
+		} else if result.Full {
+			return "Sorry, parking lot is full\n"
+		}
+		return fmt.Sprintf("Allocated slot number: %d\n", result.Slot)
+	case parser.CommandParkAt:
+		return fmt.Sprintf("Allocated slot number: %d\n", result.Slot)
+	case parser.CommandMove:
+		return fmt.Sprintf("Moved car from slot number %d to %d\n", result.FromSlot, result.Slot) +
+			formatAdmitted(result.FromSlot, result.Admitted)
+	case parser.CommandLeave:
+		return fmt.Sprintf("Slot number %d is free\n", result.Slot) + formatAdmitted(result.Slot, result.Admitted)
+	case parser.CommandStatus, parser.CommandStatusAt:
+		return Format(result.Status)
+	case parser.CommandRegNumForCarWithColor:
+		if len(result.RegNums) <= 0 {
+			return "Not found\n"
+		}
+		return fmt.Sprintf("%s\n", strings.Join(result.RegNums, ", "))
+	case parser.CommandSlotNumForCarWithColor:
+		if len(result.Slots) <= 0 {
+			return "Not found\n"
+		}
+		return fmt.Sprintf("%s\n", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(result.Slots)), ", "), "[]"))
+	case parser.CommandSlotNumForCarWithRegNum, parser.CommandSlotNumForCarWithRegNumAt:
+		if result.Slot == 0 {
+			return "Not found\n"
+		}
+		return fmt.Sprintf("%d\n", result.Slot)
+	case parser.CommandSetSlotCategory:
+		return fmt.Sprintf("Slot number %d is %s\n", result.Slot, result.Category)
+	case parser.CommandFreeSlots:
+		return formatFreeSlots(result.FreeSlots)
+	case parser.CommandCloseSlot:
+		return fmt.Sprintf("Slot number %d is closed\n", result.Slot)
+	case parser.CommandOpenSlot:
+		return fmt.Sprintf("Slot number %d is open\n", result.Slot) + formatAdmitted(result.Slot, result.Admitted)
+	case parser.CommandWaitlist:
+		return formatWaitlist(result.Waitlist)
+	case parser.CommandWaitlistRemove:
+		return fmt.Sprintf("Removed %s from the waitlist\n", result.RegNum)
+	case parser.CommandWaitlistCap:
+		if result.WaitlistCapacity <= 0 {
+			return "Waitlist is disabled\n"
+		}
+		return fmt.Sprintf("Waitlist capacity is %d\n", result.WaitlistCapacity)
+	case parser.CommandFind:
+		if len(result.Found) <= 0 {
+			return "Not found\n"
+		}
+		return Format(result.Found)
+	case parser.CommandStats:
+		return formatStats(result.Stats)
+	case parser.CommandExportStats:
+		return fmt.Sprintf("Exported statistics to %s\n", result.Path)
+	case parser.CommandSaveSnapshot:
+		return fmt.Sprintf("Saved snapshot to %s\n", result.Path)
+	case parser.CommandLoadSnapshot:
+		return fmt.Sprintf("Loaded a parking lot with %d slots from %s\n", result.LotSize, result.Path)
+	case parser.CommandDiffSnapshots:
+		return formatSnapshotDiff(result.Diff)
+	case parser.CommandWatchlistAdd:
+		return fmt.Sprintf("Added %s to the watchlist\n", result.RegNum)
+	case parser.CommandWatchlistRemove:
+		return fmt.Sprintf("Removed %s from the watchlist\n", result.RegNum)
+	case parser.CommandWatchlist:
+		return formatWatchlist(result.Watchlist)
+	case parser.CommandAlerts:
+		return formatAlertLog(result.AlertLog)
+	case parser.CommandOverstays:
+		return formatOverstays(result.Overstays)
+	case parser.CommandReserve:
+		reservation := result.Reservations[0]
+		return fmt.Sprintf("Reserved a slot for %s from %s to %s\n", reservation.RegNum,
+			reservation.From.Format(time.RFC3339), reservation.To.Format(time.RFC3339))
+	case parser.CommandCancelReservation:
+		return fmt.Sprintf("Cancelled the reservation of %s\n", result.RegNum)
+	case parser.CommandReservations:
+		return formatReservations(result.Reservations)
+	default:
+		return ""
+	}
+}
+
+// formatFreeSlots lists the free slots of a single category, or the number
+// of free slots of each category.
+func formatFreeSlots(freeSlots []FreeSlots) string {
+	if len(freeSlots) == 1 {
+		if freeSlots[0].Count <= 0 {
+			return "Not found\n"
+		}
+		return fmt.Sprintf("%s\n", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(freeSlots[0].Slots)), ", "), "[]"))
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Category    Free slots\n")
+	for _, entry := range freeSlots {
+		builder.WriteString(fmt.Sprintf("%-11s %d\n", entry.Category, entry.Count))
+	}
+	return builder.String()
+}
+
+// formatAdmitted reports the waitlisted car parked in the freed slot, if any.
+func formatAdmitted(slotID int, regNum string) string {
+	if regNum == "" {
+		return ""
+	}
+	return fmt.Sprintf("Allocated slot number: %d to %s from the waitlist\n", slotID, regNum)
+}
+
+// formatWaitlist lists the waitlisted cars in the arrival order.
+func formatWaitlist(cars []dao.Car) string {
+	if len(cars) <= 0 {
+		return "Waitlist is empty\n"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Position    Registration No    Colour\n")
+	for i, car := range cars {
+		builder.WriteString(fmt.Sprintf("%-11d %-18s %s\n", i+1, car.RegistrationNumber, car.Color))
+	}
+	return builder.String()
+}
+
+// formatStats formats the occupancy and utilization report.
+func formatStats(stats *Stats) string {
+	if stats == nil {
+		return ""
+	}
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Occupied slots: %d of %d\n", stats.Occupied, stats.Size))
+	builder.WriteString(fmt.Sprintf("Free slots: %d\n", stats.Free))
+	builder.WriteString(fmt.Sprintf("Peak occupancy: %d\n", stats.PeakOccupancy))
+	builder.WriteString(fmt.Sprintf("Cars parked: %d, left: %d\n", stats.Arrivals, stats.Departures))
+	builder.WriteString(fmt.Sprintf("Average dwell time: %s\n", formatSeconds(stats.AverageDwell)))
+	builder.WriteString(fmt.Sprintf("Median dwell time: %s\n", formatSeconds(stats.MedianDwell)))
+	if len(stats.Turnover) > 0 {
+		builder.WriteString("Slot No.    Cars parked\n")
+		for _, entry := range stats.Turnover {
+			builder.WriteString(fmt.Sprintf("%-11d %d\n", entry.Slot, entry.Cars))
+		}
+	}
+	if len(stats.TopColors) > 0 {
+		builder.WriteString("Colour      Cars parked\n")
+		for _, entry := range stats.TopColors {
+			builder.WriteString(fmt.Sprintf("%-11s %d\n", entry.Color, entry.Cars))
+		}
+	}
+	return builder.String()
+}
+
+// formatSnapshotDiff lists the cars that arrived, left or moved. Sections
+// without any cars are left out.
+func formatSnapshotDiff(diff *SnapshotDiff) string {
+	if diff == nil {
+		return ""
+	}
+	if diff.Empty() {
+		return "No changes\n"
+	}
+	builder := strings.Builder{}
+	for _, section := range []struct {
+		title string
+		rows  []dao.Status
+	}{{"Arrived", diff.Arrived}, {"Left", diff.Left}} {
+		if len(section.rows) > 0 {
+			builder.WriteString(fmt.Sprintf("%s:\n", section.title))
+			builder.WriteString(Format(section.rows))
+		}
+	}
+	if len(diff.Moved) > 0 {
+		builder.WriteString("Moved:\n")
+		builder.WriteString("Registration No    From    To\n")
+		for _, move := range diff.Moved {
+			builder.WriteString(fmt.Sprintf("%-18s %-7d %d\n", move.RegNum, move.FromSlot, move.ToSlot))
+		}
+	}
+	return builder.String()
+}
+
+// formatWatchlist lists the watchlist entries.
+func formatWatchlist(entries []WatchlistEntry) string {
+	if len(entries) <= 0 {
+		return "Watchlist is empty\n"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Registration No    Severity    Reason\n")
+	for _, entry := range entries {
+		builder.WriteString(fmt.Sprintf("%-18s %-11s %s\n", entry.RegNum, entry.Severity, entry.Reason))
+	}
+	return builder.String()
+}
+
+// formatAlerts reports the alerts raised by the command.
+func formatAlerts(alerts []Alert) string {
+	builder := strings.Builder{}
+	for _, alert := range alerts {
+		builder.WriteString(fmt.Sprintf("Alert: %s is on the watchlist (%s): %s\n", alert.RegNum, alert.Severity, alert.Reason))
+	}
+	return builder.String()
+}
+
+// formatAlertLog lists the logged alerts in the order they were raised.
+func formatAlertLog(alerts []Alert) string {
+	if len(alerts) <= 0 {
+		return "No alerts\n"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Time                      Registration No    Slot No.    Severity    Reason\n")
+	for _, alert := range alerts {
+		slot := strconv.Itoa(alert.Slot)
+		if alert.Refused {
+			slot = "Refused"
+		}
+		builder.WriteString(fmt.Sprintf("%-25s %-18s %-11s %-11s %s\n", alert.At.Format(time.RFC3339), alert.RegNum,
+			slot, alert.Severity, alert.Reason))
+	}
+	return builder.String()
+}
+
+// formatOverstays lists the cars past their stay limit.
+func formatOverstays(overstays []Overstay) string {
+	if len(overstays) <= 0 {
+		return "No overstays\n"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Slot No.    Registration No    Parked at                 Limit       Over by\n")
+	for _, overstay := range overstays {
+		builder.WriteString(fmt.Sprintf("%-11d %-18s %-25s %-11s %s\n", overstay.Slot, overstay.RegNum,
+			overstay.ParkedAt.Format(time.RFC3339), formatSeconds(overstay.Limit), formatSeconds(overstay.Over)))
+	}
+	return builder.String()
+}
+
+// formatReservations lists the reservations in the order of their windows.
+func formatReservations(reservations []Reservation) string {
+	if len(reservations) <= 0 {
+		return "No reservations\n"
+	}
+	builder := strings.Builder{}
+	builder.WriteString("Registration No    From                      To                        Held until\n")
+	for _, reservation := range reservations {
+		builder.WriteString(fmt.Sprintf("%-18s %-25s %-25s %s\n", reservation.RegNum, reservation.From.Format(time.RFC3339),
+			reservation.To.Format(time.RFC3339), reservation.HoldUntil.Format(time.RFC3339)))
+	}
+	return builder.String()
+}
+
+// formatSeconds formats the seconds as a duration rounded to the second.
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second).String()
+}
@@ -0,0 +1,384 @@
+package processor
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/plate"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrSnapshotInvalid specifies snapshot that can not be restored.
+	ErrSnapshotInvalid = common.NewError("ERR_SNAPSHOT_INVALID", common.CategoryValidation,
+		"snapshot is not valid")
+	// ErrSnapshotNotReadable specifies snapshot file that can not be read.
+	ErrSnapshotNotReadable = common.NewError("ERR_SNAPSHOT_NOT_READABLE", common.CategoryNotFound,
+		"snapshot file can not be read")
+	// ErrSnapshotNotWritable specifies snapshot file that can not be written.
+	ErrSnapshotNotWritable = common.NewError("ERR_SNAPSHOT_NOT_WRITABLE", common.CategoryNotFound,
+		"snapshot file can not be written")
+)
+
+// Snapshot is a serializable copy of the parking lot state. Only occupied
+// and reserved slots are stored, the allocator state is derived from them
+// on restore.
+type Snapshot struct {
+	Size  int        `json:"size"`
+	Slots []dao.Slot `json:"slots"`
+	// Waitlist contains the waitlisted cars in the arrival order.
+	Waitlist []dao.Car `json:"waitlist,omitempty"`
+	// Reservations contains the reservations not yet used up or released.
+	Reservations []Reservation `json:"reservations,omitempty"`
+	// Watchlist contains the flagged registration numbers and Alerts the
+	// alert log. Both are kept even if the parking lot is not created.
+	Watchlist []WatchlistEntry `json:"watchlist,omitempty"`
+	Alerts    []Alert          `json:"alerts,omitempty"`
+	// History contains the park and leave events the point-in-time queries
+	// are answered from, in the clock order. HistorySince is the time the
+	// history is cut at, if it is. Snapshots without the history record the
+	// parked cars as parked at the time they were parked at.
+	History      []HistoryEvent `json:"history,omitempty"`
+	HistorySince *time.Time     `json:"history_since,omitempty"`
+}
+
+// Snapshot captures the current state of the parking lot.
+func (e *Executor) Snapshot() Snapshot {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.snapshot()
+}
+
+func (e *Executor) snapshot() Snapshot {
+	snapshot := Snapshot{Size: e.allocator.GetSize(), Slots: make([]dao.Slot, 0)}
+	if entries := e.watchlist.list(); len(entries) > 0 {
+		snapshot.Watchlist = entries
+	}
+	if alerts := e.watchlist.alertsFor(""); len(alerts) > 0 {
+		snapshot.Alerts = alerts
+	}
+	if snapshot.Size <= 0 {
+		return snapshot
+	}
+	for _, entry := range e.storage.Slots() {
+		slot := dao.Slot{ID: entry.SlotNum, Closed: entry.Closed, ClosedReason: entry.ClosedReason}
+		if entry.Category != dao.SlotCategoryGeneral {
+			slot.Category = entry.Category
+		}
+		if entry.RegNum != "" {
+			slot.Car = &dao.Car{RegistrationNumber: entry.RegNum, Color: entry.Color, Permit: entry.Permit,
+				ParkedAt: entry.ParkedAt}
+		}
+		if slot.Car != nil || slot.Category != "" || slot.Closed {
+			snapshot.Slots = append(snapshot.Slots, slot)
+		}
+	}
+	if cars := e.waitlist.Cars(); len(cars) > 0 {
+		snapshot.Waitlist = cars
+	}
+	if reservations := e.reserving.list(); len(reservations) > 0 {
+		snapshot.Reservations = reservations
+	}
+	if events := e.history.export(); len(events) > 0 {
+		snapshot.History = events
+	}
+	if !e.history.since.IsZero() {
+		since := e.history.since
+		snapshot.HistorySince = &since
+	}
+	return snapshot
+}
+
+// Restore replaces the state of the parking lot with the snapshot. The
+// parking lot must not be created yet.
+func (e *Executor) Restore(snapshot Snapshot) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	err := e.restore(snapshot)
+	e.publishFull()
+	return err
+}
+
+func (e *Executor) restore(snapshot Snapshot) error {
+	if e.allocator.GetSize() > 0 {
+		return ErrParkingLotSizeAlreadySet
+	}
+	// Whole snapshot is validated before any of the state is replaced, so
+	// that the snapshot that is not valid leaves the state as it was.
+	snapshot, err := e.normalizeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	e.restoreWatchlist(snapshot)
+	if snapshot.Size <= 0 {
+		// Parking lot was never created.
+		return nil
+	}
+
+	e.storage.SetSize(snapshot.Size)
+	for _, slot := range snapshot.Slots {
+		if slot.Category != "" {
+			_ = e.storage.SetCategory(slot.ID, slot.Category)
+		}
+		if slot.Closed {
+			_ = e.storage.Close(slot.ID, slot.ClosedReason)
+		}
+		if slot.Car != nil {
+			// Slots and registration numbers are validated above.
+			_ = e.storage.Park(slot.ID, slot.Car)
+		}
+	}
+
+	e.allocator.SetSize(snapshot.Size)
+	occupied := 0
+	for _, slot := range snapshot.Slots {
+		if slot.Category != "" {
+			e.allocator.SetCategory(slot.ID, slot.Category)
+		}
+		if slot.Closed {
+			e.allocator.Close(slot.ID)
+		}
+		if slot.Car != nil {
+			e.allocator.MarkSlotAsAllocated(slot.ID)
+		}
+		if slot.Car != nil {
+			occupied++
+		}
+	}
+	now := e.clock()
+	e.stats.recordRestore(occupied, now)
+	if len(snapshot.History) > 0 || snapshot.HistorySince != nil {
+		since := time.Time{}
+		if snapshot.HistorySince != nil {
+			since = *snapshot.HistorySince
+		}
+		e.history.restore(snapshot.History, since)
+	} else {
+		e.recordRestoreHistory(snapshot.Slots, now)
+	}
+	e.publishLotCreated(snapshot.Size)
+	// Waitlisted cars are restored even if they exceed the configured
+	// capacity. They were admitted to the waitlist by the earlier run.
+	e.waitlist.cars = snapshot.Waitlist
+	// Reservations released since the snapshot are dropped on the next
+	// use.
+	e.reserving.reservations = snapshot.Reservations
+	return nil
+}
+
+// normalizeSnapshot validates the snapshot and returns its copy with the
+// registration numbers, colours, permits and severities in the same form as
+// the executor stores them. Snapshots written with a different plate format
+// or palette are brought to the current ones. Returns ErrSnapshotInvalid if
+// the snapshot can not be restored.
+func (e *Executor) normalizeSnapshot(snapshot Snapshot) (Snapshot, error) {
+	normalized := Snapshot{Size: snapshot.Size, Slots: make([]dao.Slot, 0, len(snapshot.Slots))}
+	watchlisted := make(map[string]bool, len(snapshot.Watchlist))
+	for _, entry := range snapshot.Watchlist {
+		var err error
+		if entry.Severity, err = ParseSeverity(string(entry.Severity)); err != nil {
+			return normalized, ErrSnapshotInvalid.WithDetail("watchlist entry %q: %s", entry.RegNum, err.Error())
+		}
+		regNum, err := plate.Normalize(e.plates, entry.RegNum)
+		if err != nil || watchlisted[regNum] {
+			return normalized, ErrSnapshotInvalid.WithDetail("watchlist entry %q is not valid or is repeated", entry.RegNum)
+		}
+		entry.RegNum = regNum
+		watchlisted[entry.RegNum] = true
+		normalized.Watchlist = append(normalized.Watchlist, entry)
+	}
+	normalized.Alerts = snapshot.Alerts
+	if len(normalized.Alerts) > maxAlerts {
+		normalized.Alerts = normalized.Alerts[len(normalized.Alerts)-maxAlerts:]
+	}
+	normalized.Alerts = append([]Alert(nil), normalized.Alerts...)
+	if snapshot.Size <= 0 {
+		return normalized, nil
+	}
+
+	seen := make(map[int]bool, len(snapshot.Slots))
+	cars := make(map[string]bool, len(snapshot.Slots)+len(snapshot.Waitlist))
+	for _, slot := range snapshot.Slots {
+		if slot.ID <= 0 || slot.ID > snapshot.Size || seen[slot.ID] {
+			return normalized, ErrSnapshotInvalid.WithDetail("slot %d is not valid for the parking lot of size %d",
+				slot.ID, snapshot.Size)
+		}
+		seen[slot.ID] = true
+		if slot.Category != "" {
+			category, err := dao.ParseSlotCategory(string(slot.Category))
+			if err != nil {
+				return normalized, ErrSnapshotInvalid.WithDetail("slot %d: %s", slot.ID, err.Error())
+			}
+			slot.Category = category
+		}
+		if slot.Closed && slot.Car != nil {
+			return normalized, ErrSnapshotInvalid.WithDetail("slot %d is both closed and occupied", slot.ID)
+		}
+		if slot.Car != nil {
+			car, err := e.normalizeCar(*slot.Car)
+			if err != nil {
+				return normalized, ErrSnapshotInvalid.WithDetail("car at slot %d: %s", slot.ID, err.Error())
+			}
+			if cars[car.RegistrationNumber] {
+				return normalized, ErrSnapshotInvalid.WithDetail("car %s is parked at more than one slot", car.RegistrationNumber)
+			}
+			cars[car.RegistrationNumber] = true
+			slot.Car = &car
+		}
+		normalized.Slots = append(normalized.Slots, slot)
+	}
+	for _, waitlisted := range snapshot.Waitlist {
+		car, err := e.normalizeCar(waitlisted)
+		if err != nil || cars[car.RegistrationNumber] {
+			return normalized, ErrSnapshotInvalid.WithDetail("waitlisted car %q is not valid, is parked or is repeated",
+				waitlisted.RegistrationNumber)
+		}
+		cars[car.RegistrationNumber] = true
+		normalized.Waitlist = append(normalized.Waitlist, car)
+	}
+	reserved := make(map[string]bool, len(snapshot.Reservations))
+	for _, reservation := range snapshot.Reservations {
+		regNum, err := plate.Normalize(e.plates, reservation.RegNum)
+		if err != nil || reserved[regNum] || !reservation.From.Before(reservation.To) ||
+			reservation.HoldUntil.Before(reservation.From) || reservation.HoldUntil.After(reservation.To) {
+			return normalized, ErrSnapshotInvalid.WithDetail("reservation of %q is not valid or is repeated", reservation.RegNum)
+		}
+		reserved[regNum] = true
+		reservation.RegNum = regNum
+		normalized.Reservations = append(normalized.Reservations, reservation)
+	}
+	normalized.HistorySince = snapshot.HistorySince
+	history, err := e.normalizeHistory(snapshot.History, normalized)
+	if err != nil {
+		return normalized, err
+	}
+	normalized.History = history
+	return normalized, nil
+}
+
+// normalizeHistory validates and normalizes the history of the snapshot.
+// Replaying the history must park the cars of the snapshot at their slots.
+func (e *Executor) normalizeHistory(events []HistoryEvent, snapshot Snapshot) ([]HistoryEvent, error) {
+	if len(events) == 0 && snapshot.HistorySince == nil {
+		return nil, nil
+	}
+	normalized := make([]HistoryEvent, 0, len(events))
+	occupied := make(map[int]string)
+	for i, event := range events {
+		if event.Slot <= 0 || event.Slot > snapshot.Size || (i > 0 && event.At.Before(events[i-1].At)) {
+			return nil, ErrSnapshotInvalid.WithDetail("history event %d at slot %d is not valid or out of order",
+				i, event.Slot)
+		}
+		if event.Car == nil {
+			delete(occupied, event.Slot)
+		} else {
+			car, err := e.normalizeCar(*event.Car)
+			if err != nil {
+				return nil, ErrSnapshotInvalid.WithDetail("history event %d: %s", i, err.Error())
+			}
+			event.Car = &car
+			occupied[event.Slot] = car.RegistrationNumber
+		}
+		normalized = append(normalized, event)
+	}
+	parked := 0
+	for _, slot := range snapshot.Slots {
+		if slot.Car == nil {
+			continue
+		}
+		parked++
+		if occupied[slot.ID] != slot.Car.RegistrationNumber {
+			return nil, ErrSnapshotInvalid.WithDetail("history does not park car %s at slot %d",
+				slot.Car.RegistrationNumber, slot.ID)
+		}
+	}
+	if parked != len(occupied) {
+		return nil, ErrSnapshotInvalid.WithDetail("history parks %d cars, the snapshot %d", len(occupied), parked)
+	}
+	return normalized, nil
+}
+
+// normalizeCar validates and canonicalizes the car details of the snapshot
+// the same way as the ones of the park commands.
+func (e *Executor) normalizeCar(car dao.Car) (dao.Car, error) {
+	var err error
+	if car.RegistrationNumber, err = plate.Normalize(e.plates, car.RegistrationNumber); err != nil {
+		return car, err
+	}
+	if car.Color, err = e.colors.Canonicalize(car.Color); err != nil {
+		return car, err
+	}
+	if car.Permit != "" {
+		if car.Permit, err = dao.ParseSlotCategory(string(car.Permit)); err != nil {
+			return car, err
+		}
+	}
+	return car, nil
+}
+
+// recordRestoreHistory records the restored cars as parked at the time they
+// were parked at, in that order. Cars without the parking time are recorded
+// as parked at the time of the restore.
+func (e *Executor) recordRestoreHistory(slots []dao.Slot, now time.Time) {
+	parked := make([]dao.Slot, 0, len(slots))
+	for _, slot := range slots {
+		if slot.Car == nil {
+			continue
+		}
+		if slot.Car.ParkedAt.IsZero() || slot.Car.ParkedAt.After(now) {
+			car := *slot.Car
+			car.ParkedAt = now
+			slot.Car = &car
+		}
+		parked = append(parked, slot)
+	}
+	sort.SliceStable(parked, func(i, j int) bool { return parked[i].Car.ParkedAt.Before(parked[j].Car.ParkedAt) })
+	for _, slot := range parked {
+		e.history.recordPark(slot.ID, slot.Car, slot.Car.ParkedAt)
+	}
+}
+
+// WriteSnapshot writes the snapshot to the writer as JSON.
+func WriteSnapshot(w io.Writer, snapshot Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot)
+}
+
+// ReadSnapshot reads the JSON snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	snapshot := Snapshot{}
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return snapshot, ErrSnapshotInvalid.WithDetail("%s", err.Error())
+	}
+	return snapshot, nil
+}
+
+// saveSnapshot writes the current state of the parking lot to the file.
+func (e *Executor) saveSnapshot(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return ErrSnapshotNotWritable.WithDetail("%s", err.Error())
+	}
+	err = WriteSnapshot(file, e.snapshot())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return ErrSnapshotNotWritable.WithDetail("%s", err.Error())
+	}
+	return nil
+}
+
+// readSnapshotFile reads the snapshot written by save_snapshot.
+func readSnapshotFile(path string) (Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Snapshot{}, ErrSnapshotNotReadable.WithDetail("%s", err.Error())
+	}
+	defer file.Close()
+	return ReadSnapshot(file)
+}
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"parking_lot/dao"
+	"sort"
+)
+
+// SnapshotDiff lists the cars that arrived, left or moved between two
+// snapshots. Cars are matched by the registration number. Waitlisted cars
+// are not compared.
+type SnapshotDiff struct {
+	// Arrived contains the cars parked only in the later snapshot.
+	Arrived []dao.Status `json:"arrived"`
+	// Left contains the cars parked only in the earlier snapshot.
+	Left []dao.Status `json:"left"`
+	// Moved contains the cars parked at different slots in the snapshots.
+	Moved []CarMove `json:"moved"`
+}
+
+// CarMove is a car parked at different slots in the compared snapshots.
+type CarMove struct {
+	RegNum   string `json:"registration_number"`
+	Color    string `json:"colour"`
+	FromSlot int    `json:"from_slot"`
+	ToSlot   int    `json:"to_slot"`
+}
+
+// Empty returns true if the snapshots park the same cars at the same slots.
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Arrived) == 0 && len(d.Left) == 0 && len(d.Moved) == 0
+}
+
+// DiffSnapshots compares the cars parked in the snapshots. Rows are ordered
+// by the slot, moves by the slot the car moved from.
+func DiffSnapshots(from Snapshot, to Snapshot) SnapshotDiff {
+	before := parkedCars(from)
+	after := parkedCars(to)
+
+	diff := SnapshotDiff{Arrived: make([]dao.Status, 0), Left: make([]dao.Status, 0), Moved: make([]CarMove, 0)}
+	for regNum, entry := range after {
+		previous, ok := before[regNum]
+		if !ok {
+			diff.Arrived = append(diff.Arrived, entry)
+		} else if previous.SlotNum != entry.SlotNum {
+			diff.Moved = append(diff.Moved, CarMove{RegNum: regNum, Color: entry.Color, FromSlot: previous.SlotNum,
+				ToSlot: entry.SlotNum})
+		}
+	}
+	for regNum, entry := range before {
+		if _, ok := after[regNum]; !ok {
+			diff.Left = append(diff.Left, entry)
+		}
+	}
+
+	sort.Slice(diff.Arrived, func(i, j int) bool { return diff.Arrived[i].SlotNum < diff.Arrived[j].SlotNum })
+	sort.Slice(diff.Left, func(i, j int) bool { return diff.Left[i].SlotNum < diff.Left[j].SlotNum })
+	sort.Slice(diff.Moved, func(i, j int) bool { return diff.Moved[i].FromSlot < diff.Moved[j].FromSlot })
+	return diff
+}
+
+// parkedCars maps the registration numbers of the parked cars to their rows.
+func parkedCars(snapshot Snapshot) map[string]dao.Status {
+	cars := make(map[string]dao.Status, len(snapshot.Slots))
+	for _, slot := range snapshot.Slots {
+		if slot.Car == nil {
+			continue
+		}
+		category := slot.Category
+		if category == "" {
+			category = dao.SlotCategoryGeneral
+		}
+		cars[slot.Car.RegistrationNumber] = dao.Status{SlotNum: slot.ID, RegNum: slot.Car.RegistrationNumber,
+			Color: slot.Car.Color, Category: category, Permit: slot.Car.Permit, ParkedAt: slot.Car.ParkedAt}
+	}
+	return cars
+}
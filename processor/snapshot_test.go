@@ -0,0 +1,275 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecutor_SnapshotRestore(t *testing.T) {
+	executor := newTestExecutor(4)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"2", "vip"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCloseSlot, []string{"4", "Repainting"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1235", "Red"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+
+	buffer := bytes.Buffer{}
+	if err := WriteSnapshot(&buffer, executor.Snapshot()); err != nil {
+		t.Errorf("WriteSnapshot() Error %v", err)
+	}
+	snapshot, err := ReadSnapshot(&buffer)
+	if err != nil {
+		t.Errorf("ReadSnapshot() Error %v", err)
+	}
+
+	restored := newTestExecutor(0)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Errorf("Restore() Error %v", err)
+	}
+	if !reflect.DeepEqual(restored.Snapshot(), executor.Snapshot()) {
+		t.Errorf("Restore() got %+v want %+v", restored.Snapshot(), executor.Snapshot())
+	}
+
+	// Freed slot 1 is allocated first. Slot 2 is reserved and slot 4 is closed.
+	for _, expected := range []int{1, 0} {
+		result, _ := restored.Execute(parser.NewCommand(parser.CommandPark, []string{fmt.Sprintf("KA-01-HH-%04d", expected), "Red"}))
+		if result.Slot != expected {
+			t.Errorf("Execute() got %d want %d", result.Slot, expected)
+		}
+	}
+}
+
+func TestExecutor_RestoreInvalidSnapshot(t *testing.T) {
+	executor := newTestExecutor(0)
+	snapshot := Snapshot{Size: 2, Slots: []dao.Slot{{ID: 3, Car: &dao.Car{RegistrationNumber: "KA-01-HH-1234", Color: "White"}}}}
+	if err := executor.Restore(snapshot); !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("Restore() Error got %v want %v", err, ErrSnapshotInvalid)
+	}
+}
+
+func TestExecutor_RestoreNormalizesSnapshot(t *testing.T) {
+	executor := newTestExecutor(0)
+	snapshot := Snapshot{
+		Size:      2,
+		Slots:     []dao.Slot{{ID: 1, Car: &dao.Car{RegistrationNumber: "ka01hh1234", Color: "light  CORAL", Permit: "ev"}}},
+		Waitlist:  []dao.Car{{RegistrationNumber: "ka-01-hh-1235", Color: "gray"}},
+		Watchlist: []WatchlistEntry{{RegNum: "ka01hh1236", Severity: SeverityHigh, Reason: "Stolen"}},
+	}
+	if err := executor.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() Error %v", err)
+	}
+	got := executor.Snapshot()
+	if car := got.Slots[0].Car; car.RegistrationNumber != "KA-01-HH-1234" || car.Color != "Light Coral" || car.Permit != dao.SlotCategoryEV {
+		t.Errorf("Restore() got car %+v want it normalized", *car)
+	}
+	if got.Waitlist[0].RegistrationNumber != "KA-01-HH-1235" || got.Waitlist[0].Color != "Grey" {
+		t.Errorf("Restore() got waitlisted car %+v want it normalized", got.Waitlist[0])
+	}
+	if got.Watchlist[0].RegNum != "KA-01-HH-1236" {
+		t.Errorf("Restore() got watchlist entry %+v want it normalized", got.Watchlist[0])
+	}
+	if result, _ := executor.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNum, []string{"KA-01-HH-1234"})); result.Slot != 1 {
+		t.Errorf("Execute() got slot %d want %d", result.Slot, 1)
+	}
+}
+
+func TestExecutor_RestoreInvalidSnapshotKeepsState(t *testing.T) {
+	executor := newTestExecutor(0)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-1234", "high", "Stolen"}))
+	for _, snapshot := range []Snapshot{
+		{Size: 1, Watchlist: []WatchlistEntry{{RegNum: "KA-01-HH-9999", Severity: SeverityLow}},
+			Slots: []dao.Slot{{ID: 2}}},
+		{Size: 2, Watchlist: []WatchlistEntry{{RegNum: "KA-01-HH-9999", Severity: SeverityLow}},
+			Slots: []dao.Slot{{ID: 1, Car: &dao.Car{RegistrationNumber: "KA-01-HH-1235", Color: "Red"}},
+				{ID: 2, Car: &dao.Car{RegistrationNumber: "ka-01-hh-1235", Color: "Red"}}}},
+		{Size: 1, Watchlist: []WatchlistEntry{{RegNum: "KA-01-HH-9999", Severity: SeverityLow}},
+			Waitlist: []dao.Car{{RegistrationNumber: "not valid!", Color: "Red"}}},
+	} {
+		if err := executor.Restore(snapshot); !errors.Is(err, ErrSnapshotInvalid) {
+			t.Errorf("Restore() Error got %v want %v", err, ErrSnapshotInvalid)
+		}
+		if watchlist := executor.Snapshot().Watchlist; len(watchlist) != 1 || watchlist[0].RegNum != "KA-01-HH-1234" {
+			t.Errorf("Restore() changed the watchlist to %+v", watchlist)
+		}
+	}
+}
+
+func TestExecutor_SnapshotRestoreHistory(t *testing.T) {
+	executor := newTestExecutor(2)
+	// Test clock reads 09:00, 09:01, ... in the order of the commands.
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	parkWithPermit(&executor, "KA-01-HH-0002", "")
+	snapshot := executor.Snapshot()
+	if len(snapshot.History) != 3 || snapshot.HistorySince != nil {
+		t.Fatalf("Snapshot() got history %+v since %v want 3 events", snapshot.History, snapshot.HistorySince)
+	}
+
+	restored := newTestExecutor(0)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() got %v", err)
+	}
+	result, err := restored.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNumAt,
+		[]string{"KA-01-HH-0001", "2020-01-01T09:00:30Z"}))
+	if err != nil || result.Slot != 1 {
+		t.Errorf("Execute(slot_number_for_registration_number_at) got %d, %v want %d", result.Slot, err, 1)
+	}
+
+	// History must park the cars of the snapshot.
+	snapshot.History = snapshot.History[:2]
+	invalid := newTestExecutor(0)
+	if err := invalid.Restore(snapshot); !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("Restore() Error got %v want %v", err, ErrSnapshotInvalid)
+	}
+}
+
+func TestExecutor_SnapshotRestoreExpiredHistory(t *testing.T) {
+	allocator := NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &storage, WithClock(newTestClock(time.Minute).Now),
+		WithHistoryRetention(time.Hour))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"3"}))
+	// Car parked at slot 1 throughout is kept at every cut.
+	parkWithPermit(&executor, "KA-01-HH-0001", "")
+	cuts := make(map[time.Time]bool)
+	for i := 0; i < 3000; i++ {
+		parkWithPermit(&executor, fmt.Sprintf("KA-01-HH-%04d", i+2), "")
+		_, _ = executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"2"}))
+		cuts[executor.history.since] = true
+	}
+	if len(cuts) < 3 {
+		t.Fatalf("history cut %d times want at least twice", len(cuts)-1)
+	}
+
+	restored := newTestExecutor(0)
+	if err := restored.Restore(executor.Snapshot()); err != nil {
+		t.Fatalf("Restore() got %v", err)
+	}
+	at := executor.history.since.Format(time.RFC3339)
+	result, err := restored.Execute(parser.NewCommand(parser.CommandSlotNumForCarWithRegNumAt, []string{"KA-01-HH-0001", at}))
+	if err != nil || result.Slot != 1 {
+		t.Errorf("Execute(slot_number_for_registration_number_at) got %d, %v want %d", result.Slot, err, 1)
+	}
+}
+
+func TestExecutor_RestoreCreatedParkingLot(t *testing.T) {
+	executor := newTestExecutor(2)
+	if err := executor.Restore(Snapshot{Size: 1}); err != ErrParkingLotSizeAlreadySet {
+		t.Errorf("Restore() Error got %v want %v", err, ErrParkingLotSizeAlreadySet)
+	}
+}
+
+func TestExecutor_RestoreWaitlist(t *testing.T) {
+	executor := newTestExecutor(0)
+	snapshot := Snapshot{
+		Size:     1,
+		Slots:    []dao.Slot{{ID: 1, Car: &dao.Car{RegistrationNumber: "KA-01-HH-1234", Color: "White"}}},
+		Waitlist: []dao.Car{{RegistrationNumber: "KA-01-HH-1235", Color: "Red"}},
+	}
+	if err := executor.Restore(snapshot); err != nil {
+		t.Errorf("Restore() Error %v", err)
+	}
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if result.Admitted != "KA-01-HH-1235" {
+		t.Errorf("Execute() got admitted %q want %q", result.Admitted, "KA-01-HH-1235")
+	}
+}
+
+func TestExecutor_ExecuteSaveLoadSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "lot.json")
+
+	executor := newTestExecutor(3)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandSetSlotCategory, []string{"3", "ev"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1234", "White"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1235", "Red"}))
+	if _, err := executor.Execute(parser.NewCommand(parser.CommandSaveSnapshot, []string{path})); err != nil {
+		t.Fatalf("Execute(save_snapshot) got %v", err)
+	}
+
+	loaded := newTestExecutor(0)
+	result, err := loaded.Execute(parser.NewCommand(parser.CommandLoadSnapshot, []string{path}))
+	if err != nil {
+		t.Fatalf("Execute(load_snapshot) got %v", err)
+	}
+	if result.LotSize != 3 || result.Path != path {
+		t.Errorf("Execute(load_snapshot) got %+v", result)
+	}
+	if !reflect.DeepEqual(loaded.Snapshot(), executor.Snapshot()) {
+		t.Errorf("Execute(load_snapshot) got %+v want %+v", loaded.Snapshot(), executor.Snapshot())
+	}
+	// Slot 3 is reserved for the cars with ev permit.
+	if result, _ := loaded.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-1236", "Red"})); !result.Full {
+		t.Errorf("Execute(park) got %+v want full", result)
+	}
+
+	if _, err := loaded.Execute(parser.NewCommand(parser.CommandLoadSnapshot, []string{path})); err != ErrParkingLotSizeAlreadySet {
+		t.Errorf("Execute(load_snapshot) got %v want %v", err, ErrParkingLotSizeAlreadySet)
+	}
+	fresh := newTestExecutor(0)
+	_, err = fresh.Execute(parser.NewCommand(parser.CommandLoadSnapshot, []string{filepath.Join(dir, "missing.json")}))
+	if !errors.Is(err, ErrSnapshotNotReadable) {
+		t.Errorf("Execute(load_snapshot) got %v want %v", err, ErrSnapshotNotReadable)
+	}
+	_, err = executor.Execute(parser.NewCommand(parser.CommandSaveSnapshot, []string{filepath.Join(dir, "missing", "lot.json")}))
+	if !errors.Is(err, ErrSnapshotNotWritable) {
+		t.Errorf("Execute(save_snapshot) got %v want %v", err, ErrSnapshotNotWritable)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	car := func(regNum string) *dao.Car {
+		return &dao.Car{RegistrationNumber: regNum, Color: "White"}
+	}
+	from := Snapshot{Size: 4, Slots: []dao.Slot{
+		{ID: 1, Car: car("KA-01-HH-0001")},
+		{ID: 2, Car: car("KA-01-HH-0002")},
+		{ID: 3, Car: car("KA-01-HH-0003")},
+	}}
+	to := Snapshot{Size: 4, Slots: []dao.Slot{
+		{ID: 1, Car: car("KA-01-HH-0001")},
+		{ID: 2, Car: car("KA-01-HH-0004"), Category: dao.SlotCategoryEV},
+		{ID: 4, Car: car("KA-01-HH-0003")},
+	}}
+
+	diff := DiffSnapshots(from, to)
+	want := SnapshotDiff{
+		Arrived: []dao.Status{{SlotNum: 2, RegNum: "KA-01-HH-0004", Color: "White", Category: dao.SlotCategoryEV}},
+		Left:    []dao.Status{{SlotNum: 2, RegNum: "KA-01-HH-0002", Color: "White", Category: dao.SlotCategoryGeneral}},
+		Moved:   []CarMove{{RegNum: "KA-01-HH-0003", Color: "White", FromSlot: 3, ToSlot: 4}},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("DiffSnapshots() got %+v want %+v", diff, want)
+	}
+	if !DiffSnapshots(from, from).Empty() {
+		t.Errorf("DiffSnapshots() of the same snapshot got %+v want empty", DiffSnapshots(from, from))
+	}
+
+	got := FormatResult(Result{Command: parser.CommandDiffSnapshots, Diff: &diff})
+	expected := "Arrived:\n" +
+		"Slot No.    Registration No    Category    Colour\n" +
+		"2           KA-01-HH-0004      ev          White\n" +
+		"Left:\n" +
+		"Slot No.    Registration No    Colour\n" +
+		"2           KA-01-HH-0002      White\n" +
+		"Moved:\n" +
+		"Registration No    From    To\n" +
+		"KA-01-HH-0003      3       4\n"
+	if got != expected {
+		t.Errorf("FormatResult() got %q want %q", got, expected)
+	}
+}
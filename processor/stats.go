@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"encoding/json"
+	"io"
+	"parking_lot/common"
+	"parking_lot/dao"
+	"sort"
+	"time"
+)
+
+// ErrStatsNotWritable specifies statistics that could not be exported to the file.
+var ErrStatsNotWritable = common.NewError("ERR_STATS_NOT_WRITABLE", common.CategoryNotFound,
+	"statistics could not be written to the file")
+
+// TopColorsCount is the number of the most common colours in the statistics.
+const TopColorsCount = 5
+
+// Clock returns the current time. Executor reads the time through the clock
+// so that the time dependent results can be reproduced.
+type Clock func() time.Time
+
+// Stats is the occupancy and utilization report of the parking lot.
+type Stats struct {
+	Size     int `json:"size"`
+	Occupied int `json:"occupied"`
+	Free     int `json:"free"`
+	// PeakOccupancy is the highest number of slots occupied at once and
+	// PeakAt is the time it was first reached. Nil until a car is parked.
+	PeakOccupancy int        `json:"peak_occupancy"`
+	PeakAt        *time.Time `json:"peak_at,omitempty"`
+	// Arrivals and Departures count the cars parked and left.
+	Arrivals   int `json:"arrivals"`
+	Departures int `json:"departures"`
+	// AverageDwell and MedianDwell are the time the departed cars stayed
+	// parked for, in seconds.
+	AverageDwell float64 `json:"average_dwell_seconds"`
+	MedianDwell  float64 `json:"median_dwell_seconds"`
+	// Turnover lists the number of cars parked at each slot ever used.
+	Turnover []SlotTurnover `json:"turnover"`
+	// TopColors lists the most common colours of the parked cars.
+	TopColors []ColorCount `json:"top_colours"`
+}
+
+// SlotTurnover is the number of cars parked at the slot.
+type SlotTurnover struct {
+	Slot int `json:"slot"`
+	Cars int `json:"cars"`
+}
+
+// ColorCount is the number of cars of the colour parked.
+type ColorCount struct {
+	Color string `json:"colour"`
+	Cars  int    `json:"cars"`
+}
+
+// statsRecorder accumulates the park and leave events the statistics are
+// derived from.
+type statsRecorder struct {
+	occupied   int
+	peak       int
+	peakAt     time.Time
+	arrivals   int
+	departures int
+	dwells     []time.Duration
+	turnover   map[int]int
+	colors     map[string]int
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{
+		turnover: make(map[int]int),
+		colors:   make(map[string]int),
+	}
+}
+
+// recordPark records the car parked at the slot.
+func (r *statsRecorder) recordPark(slotID int, car *dao.Car) {
+	r.occupied++
+	r.arrivals++
+	r.turnover[slotID]++
+	r.colors[car.Color]++
+	if r.occupied > r.peak {
+		r.peak = r.occupied
+		r.peakAt = car.ParkedAt
+	}
+}
+
+// recordLeave records the car left at the time. Cars without the parking
+// time (Eg: restored from the snapshots without it) do not count towards the
+// dwell times.
+func (r *statsRecorder) recordLeave(car *dao.Car, at time.Time) {
+	r.occupied--
+	r.departures++
+	if !car.ParkedAt.IsZero() {
+		r.dwells = append(r.dwells, at.Sub(car.ParkedAt))
+	}
+}
+
+// recordRestore records the cars already parked when the state is restored.
+func (r *statsRecorder) recordRestore(occupied int, at time.Time) {
+	r.occupied = occupied
+	if occupied > r.peak {
+		r.peak = occupied
+		r.peakAt = at
+	}
+}
+
+// stats builds the report. Free slots are counted by the allocator.
+func (r *statsRecorder) stats(allocator Allocator) Stats {
+	stats := Stats{
+		Size:          allocator.GetSize(),
+		Occupied:      r.occupied,
+		PeakOccupancy: r.peak,
+		Arrivals:      r.arrivals,
+		Departures:    r.departures,
+		Turnover:      make([]SlotTurnover, 0, len(r.turnover)),
+		TopColors:     make([]ColorCount, 0, len(r.colors)),
+	}
+	if !r.peakAt.IsZero() {
+		peakAt := r.peakAt
+		stats.PeakAt = &peakAt
+	}
+	for _, category := range dao.SlotCategories {
+		stats.Free += allocator.FreeCount(category)
+	}
+
+	if len(r.dwells) > 0 {
+		dwells := make([]time.Duration, len(r.dwells))
+		copy(dwells, r.dwells)
+		sort.Slice(dwells, func(i, j int) bool { return dwells[i] < dwells[j] })
+		total := time.Duration(0)
+		for _, dwell := range dwells {
+			total += dwell
+		}
+		stats.AverageDwell = total.Seconds() / float64(len(dwells))
+		middle := len(dwells) / 2
+		if len(dwells)%2 == 0 {
+			stats.MedianDwell = (dwells[middle-1] + dwells[middle]).Seconds() / 2
+		} else {
+			stats.MedianDwell = dwells[middle].Seconds()
+		}
+	}
+
+	for slotID, cars := range r.turnover {
+		stats.Turnover = append(stats.Turnover, SlotTurnover{Slot: slotID, Cars: cars})
+	}
+	sort.Slice(stats.Turnover, func(i, j int) bool { return stats.Turnover[i].Slot < stats.Turnover[j].Slot })
+
+	for color, cars := range r.colors {
+		stats.TopColors = append(stats.TopColors, ColorCount{Color: color, Cars: cars})
+	}
+	sort.Slice(stats.TopColors, func(i, j int) bool {
+		if stats.TopColors[i].Cars != stats.TopColors[j].Cars {
+			return stats.TopColors[i].Cars > stats.TopColors[j].Cars
+		}
+		return stats.TopColors[i].Color < stats.TopColors[j].Color
+	})
+	if len(stats.TopColors) > TopColorsCount {
+		stats.TopColors = stats.TopColors[:TopColorsCount]
+	}
+	return stats
+}
+
+// WriteStats writes the statistics to the writer as JSON.
+func WriteStats(w io.Writer, stats Stats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(stats)
+}
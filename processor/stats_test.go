@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"parking_lot/dao"
+	"parking_lot/parser"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecutor_ExecuteStats(t *testing.T) {
+	executor := newTestExecutor(4)
+	commands := []parser.Command{
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0002", "White"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0003", "Red"}),
+		parser.NewCommand(parser.CommandLeave, []string{"1"}),
+		parser.NewCommand(parser.CommandLeave, []string{"2"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0004", "Blue"}),
+		parser.NewCommand(parser.CommandLeave, []string{"1"}),
+	}
+	for _, command := range commands {
+		if _, err := executor.Execute(command); err != nil {
+			t.Fatalf("Execute() Error %v", err)
+		}
+	}
+
+	result, err := executor.Execute(parser.NewCommand(parser.CommandStats, nil))
+	if err != nil {
+		t.Fatalf("Execute() Error %v", err)
+	}
+	expected := Stats{
+		Size:          4,
+		Occupied:      1,
+		Free:          3,
+		PeakOccupancy: 3,
+		PeakAt:        timePointer(time.Date(2020, time.January, 1, 9, 2, 0, 0, time.UTC)),
+		Arrivals:      4,
+		Departures:    3,
+		AverageDwell:  140,
+		MedianDwell:   180,
+		Turnover:      []SlotTurnover{{Slot: 1, Cars: 2}, {Slot: 2, Cars: 1}, {Slot: 3, Cars: 1}},
+		TopColors:     []ColorCount{{Color: "White", Cars: 2}, {Color: "Blue", Cars: 1}, {Color: "Red", Cars: 1}},
+	}
+	if !reflect.DeepEqual(*result.Stats, expected) {
+		t.Errorf("Execute() got %+v want %+v", *result.Stats, expected)
+	}
+}
+
+func TestExecutor_ExecuteExportStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	executor := newTestExecutor(2)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}))
+	path := filepath.Join(dir, "stats.json")
+	if _, err := executor.Execute(parser.NewCommand(parser.CommandExportStats, []string{path})); err != nil {
+		t.Fatalf("Execute() Error %v", err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := Stats{}
+	if err := json.Unmarshal(content, &stats); err != nil {
+		t.Errorf("Unmarshal() Error %v", err)
+	}
+	if stats.Occupied != 1 || stats.Free != 1 {
+		t.Errorf("Execute() exported %+v", stats)
+	}
+
+	_, err = executor.Execute(parser.NewCommand(parser.CommandExportStats, []string{filepath.Join(dir, "missing", "stats.json")}))
+	if !errors.Is(err, ErrStatsNotWritable) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrStatsNotWritable)
+	}
+}
+
+func TestStatsRecorder_RecordLeaveWithoutParkingTime(t *testing.T) {
+	recorder := newStatsRecorder()
+	at := time.Date(2020, time.January, 1, 10, 0, 0, 0, time.UTC)
+	recorder.recordPark(1, &dao.Car{RegistrationNumber: "KA-01-HH-0001", Color: "White"})
+	recorder.recordLeave(&dao.Car{RegistrationNumber: "KA-01-HH-0001", Color: "White"}, at)
+	recorder.recordPark(1, &dao.Car{RegistrationNumber: "KA-01-HH-0002", Color: "Red", ParkedAt: at.Add(-time.Hour)})
+	recorder.recordLeave(&dao.Car{RegistrationNumber: "KA-01-HH-0002", Color: "Red", ParkedAt: at.Add(-time.Hour)}, at)
+
+	allocator := newAllocator()
+	allocator.SetSize(1)
+	stats := recorder.stats(allocator)
+	if stats.Departures != 2 || stats.AverageDwell != 3600 || stats.MedianDwell != 3600 {
+		t.Errorf("stats() got %+v want 2 departures dwelling 3600 seconds", stats)
+	}
+}
+
+func TestExecutor_ProcessKeepsStats(t *testing.T) {
+	executor := newTestExecutor(0)
+	tokenizer := parser.NewTokenizer(strings.NewReader("create_parking_lot 2\npark KA-01-HH-0001 White\nstats\n"))
+	for i := 0; i < 2; i++ {
+		if _, err := executor.Process(&tokenizer); err != nil {
+			t.Fatalf("Process() Error %v", err)
+		}
+	}
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandStats, nil))
+	if result.Stats.Arrivals != 1 || result.Stats.PeakAt == nil {
+		t.Errorf("Execute() got %+v want 1 arrival and the peak time", *result.Stats)
+	}
+
+	out, err := json.Marshal(Stats{})
+	if err != nil || strings.Contains(string(out), "peak_at") {
+		t.Errorf("Marshal() got %s, %v want peak_at omitted", out, err)
+	}
+}
+
+func timePointer(t time.Time) *time.Time {
+	return &t
+}
@@ -6,14 +6,33 @@ import (
 	"strings"
 )
 
+// Format formats the status of the occupied and closed slots. Category of the
+// slots is shown only if the parking lot has reserved slots.
 func Format(status []dao.Status) string {
+	withCategory := false
+	for _, entry := range status {
+		withCategory = withCategory || (entry.Category != "" && entry.Category != dao.SlotCategoryGeneral)
+	}
+
 	builder := strings.Builder{}
-	builder.WriteString("Slot No.    Registration No    Colour\n")
+	if withCategory {
+		builder.WriteString("Slot No.    Registration No    Category    Colour\n")
+	} else {
+		builder.WriteString("Slot No.    Registration No    Colour\n")
+	}
 	for _, entry := range status {
+		if entry.Closed {
+			builder.WriteString(fmt.Sprintf("%-11d Closed: %s\n", entry.SlotNum, entry.ClosedReason))
+			continue
+		}
 		if entry.RegNum == "" || entry.Color == "" {
 			continue
 		}
-		builder.WriteString(fmt.Sprintf("%-11d %-18s %s\n", entry.SlotNum, entry.RegNum, entry.Color))
+		if withCategory {
+			builder.WriteString(fmt.Sprintf("%-11d %-18s %-11s %s\n", entry.SlotNum, entry.RegNum, entry.Category, entry.Color))
+		} else {
+			builder.WriteString(fmt.Sprintf("%-11d %-18s %s\n", entry.SlotNum, entry.RegNum, entry.Color))
+		}
 	}
 	return builder.String()
 }
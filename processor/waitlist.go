@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"parking_lot/common"
+	"parking_lot/dao"
+)
+
+var (
+	// ErrWaitlistFull specifies waitlist that reached its capacity.
+	ErrWaitlistFull = common.NewError("ERR_WAITLIST_FULL", common.CategoryCapacity,
+		"waitlist is full")
+	// ErrNotWaitlisted specifies car that is not in the waitlist.
+	ErrNotWaitlisted = common.NewError("ERR_NOT_WAITLISTED", common.CategoryNotFound,
+		"car is not in the waitlist")
+	// ErrWaitlistCapacityInvalid specifies capacity lower than the number of waitlisted cars.
+	ErrWaitlistCapacityInvalid = common.NewError("ERR_WAITLIST_CAPACITY_INVALID", common.CategoryValidation,
+		"waitlist capacity must not be negative or lower than the number of waitlisted cars")
+)
+
+// Waitlist queues the cars arriving at the full parking lot in the arrival
+// order. Waitlist with zero capacity is disabled.
+type Waitlist struct {
+	capacity int
+	cars     []dao.Car
+}
+
+// Enqueue adds the car to the end of the queue. Returns the position of the
+// car in the queue, starting at 1.
+func (w *Waitlist) Enqueue(car dao.Car) (int, error) {
+	if w.Position(car.RegistrationNumber) > 0 {
+		return 0, dao.ErrDuplicateRegNum.WithDetail("car %s is already in the waitlist", car.RegistrationNumber)
+	}
+	if len(w.cars) >= w.capacity {
+		return 0, ErrWaitlistFull
+	}
+	w.cars = append(w.cars, car)
+	return len(w.cars), nil
+}
+
+// Remove removes the car with the registration number from the queue.
+func (w *Waitlist) Remove(regNum string) error {
+	position := w.Position(regNum)
+	if position == 0 {
+		return ErrNotWaitlisted.WithDetail("car %s is not in the waitlist", regNum)
+	}
+	w.cars = append(w.cars[:position-1], w.cars[position:]...)
+	return nil
+}
+
+// Position returns the position of the car in the queue, starting at 1.
+// Returns 0 if the car is not in the queue.
+func (w *Waitlist) Position(regNum string) int {
+	for i, car := range w.cars {
+		if car.RegistrationNumber == regNum {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Next removes and returns the first car in the queue for which eligible
+// returns true.
+func (w *Waitlist) Next(eligible func(car *dao.Car) bool) (dao.Car, bool) {
+	for i := range w.cars {
+		if eligible(&w.cars[i]) {
+			car := w.cars[i]
+			w.cars = append(w.cars[:i], w.cars[i+1:]...)
+			return car, true
+		}
+	}
+	return dao.Car{}, false
+}
+
+// Cars returns the waitlisted cars in the arrival order.
+func (w *Waitlist) Cars() []dao.Car {
+	cars := make([]dao.Car, len(w.cars))
+	copy(cars, w.cars)
+	return cars
+}
+
+// Capacity returns the maximum length of the queue.
+func (w *Waitlist) Capacity() int {
+	return w.capacity
+}
+
+// SetCapacity sets the maximum length of the queue. Zero disables the waitlist.
+func (w *Waitlist) SetCapacity(capacity int) error {
+	if capacity < 0 || capacity < len(w.cars) {
+		return ErrWaitlistCapacityInvalid.WithDetail("capacity %d must not be negative or lower than %d waitlisted cars",
+			capacity, len(w.cars))
+	}
+	w.capacity = capacity
+	return nil
+}
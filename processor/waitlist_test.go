@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"errors"
+	"parking_lot/dao"
+	"reflect"
+	"testing"
+)
+
+func TestWaitlist_Enqueue(t *testing.T) {
+	w := Waitlist{}
+	if _, err := w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0001"}); err != ErrWaitlistFull {
+		t.Errorf("Enqueue() Error got %v want %v", err, ErrWaitlistFull)
+	}
+
+	_ = w.SetCapacity(2)
+	for i, regNum := range []string{"KA-01-HH-0001", "KA-01-HH-0002"} {
+		position, err := w.Enqueue(dao.Car{RegistrationNumber: regNum})
+		if err != nil || position != i+1 {
+			t.Errorf("Enqueue() got %d, %v want %d", position, err, i+1)
+		}
+	}
+	if _, err := w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0001"}); !errors.Is(err, dao.ErrDuplicateRegNum) {
+		t.Errorf("Enqueue() Error got %v want %v", err, dao.ErrDuplicateRegNum)
+	}
+	if _, err := w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0003"}); err != ErrWaitlistFull {
+		t.Errorf("Enqueue() Error got %v want %v", err, ErrWaitlistFull)
+	}
+	if err := w.SetCapacity(1); !errors.Is(err, ErrWaitlistCapacityInvalid) {
+		t.Errorf("SetCapacity() Error got %v want %v", err, ErrWaitlistCapacityInvalid)
+	}
+}
+
+func TestWaitlist_Next(t *testing.T) {
+	w := Waitlist{}
+	_ = w.SetCapacity(3)
+	_, _ = w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0001"})
+	_, _ = w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0002", Permit: dao.SlotCategoryEV})
+	_, _ = w.Enqueue(dao.Car{RegistrationNumber: "KA-01-HH-0003"})
+
+	car, ok := w.Next(func(car *dao.Car) bool { return car.Permit == dao.SlotCategoryEV })
+	if !ok || car.RegistrationNumber != "KA-01-HH-0002" {
+		t.Errorf("Next() got %+v, %v", car, ok)
+	}
+	if err := w.Remove("KA-01-HH-0002"); !errors.Is(err, ErrNotWaitlisted) {
+		t.Errorf("Remove() Error got %v want %v", err, ErrNotWaitlisted)
+	}
+	if err := w.Remove("KA-01-HH-0001"); err != nil {
+		t.Errorf("Remove() Error %v", err)
+	}
+	expected := []dao.Car{{RegistrationNumber: "KA-01-HH-0003"}}
+	if !reflect.DeepEqual(w.Cars(), expected) {
+		t.Errorf("Cars() got %+v want %+v", w.Cars(), expected)
+	}
+}
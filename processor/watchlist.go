@@ -0,0 +1,185 @@
+package processor
+
+import (
+	"parking_lot/common"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrUnknownSeverity specifies watchlist severity that is not supported.
+	ErrUnknownSeverity = common.NewError("ERR_UNKNOWN_SEVERITY", common.CategoryValidation,
+		"severity must be one of low, medium, high, blocked")
+	// ErrNotWatchlisted specifies registration number that is not in the watchlist.
+	ErrNotWatchlisted = common.NewError("ERR_NOT_WATCHLISTED", common.CategoryNotFound,
+		"registration number is not in the watchlist")
+	// ErrRegistrationNumberBlocked specifies car refused parking in the strict
+	// watchlist mode.
+	ErrRegistrationNumberBlocked = common.NewError("ERR_REGISTRATION_NUMBER_BLOCKED", common.CategoryValidation,
+		"registration number is blocked by the watchlist")
+)
+
+// maxAlerts is the number of the most recent alerts kept in the alert log.
+const maxAlerts = 1000
+
+// Severity tells how serious a watchlisted registration number is. Cars
+// with the blocked severity are refused parking in the strict mode.
+type Severity string
+
+const (
+	SeverityLow     Severity = "low"
+	SeverityMedium  Severity = "medium"
+	SeverityHigh    Severity = "high"
+	SeverityBlocked Severity = "blocked"
+)
+
+// ParseSeverity returns the severity with the name.
+func ParseSeverity(name string) (Severity, error) {
+	for _, severity := range []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityBlocked} {
+		if string(severity) == name {
+			return severity, nil
+		}
+	}
+	return "", ErrUnknownSeverity.WithDetail("unknown severity %q, must be one of low, medium, high, blocked", name)
+}
+
+// WatchlistEntry is a flagged registration number.
+type WatchlistEntry struct {
+	RegNum   string   `json:"registration_number"`
+	Severity Severity `json:"severity"`
+	// Reason tells why the registration number is flagged (Eg: Stolen).
+	Reason string `json:"reason"`
+}
+
+// Alert reports a watchlisted car parked, or refused parking in the strict
+// mode.
+type Alert struct {
+	At       time.Time `json:"at"`
+	RegNum   string    `json:"registration_number"`
+	Color    string    `json:"colour"`
+	Severity Severity  `json:"severity"`
+	Reason   string    `json:"reason"`
+	// Slot is the slot the car is parked at. Zero when the car is refused.
+	Slot    int  `json:"slot,omitempty"`
+	Refused bool `json:"refused,omitempty"`
+}
+
+// watchlist holds the flagged registration numbers and the log of the
+// alerts they raised.
+type watchlist struct {
+	strict  bool
+	entries map[string]WatchlistEntry
+	alerts  []Alert
+	// raised counts the alerts raised, including the ones dropped from the log.
+	raised int
+}
+
+// add adds the entry or replaces the entry of the same registration number.
+func (w *watchlist) add(entry WatchlistEntry) {
+	if w.entries == nil {
+		w.entries = make(map[string]WatchlistEntry)
+	}
+	w.entries[entry.RegNum] = entry
+}
+
+func (w *watchlist) remove(regNum string) error {
+	if _, ok := w.entries[regNum]; !ok {
+		return ErrNotWatchlisted.WithDetail("registration number %s is not in the watchlist", regNum)
+	}
+	delete(w.entries, regNum)
+	return nil
+}
+
+// lookup returns the entry of the registration number.
+func (w *watchlist) lookup(regNum string) (WatchlistEntry, bool) {
+	entry, ok := w.entries[regNum]
+	return entry, ok
+}
+
+// refuses returns true if the car with the entry is refused parking.
+func (w *watchlist) refuses(entry WatchlistEntry) bool {
+	return w.strict && entry.Severity == SeverityBlocked
+}
+
+// list returns the entries sorted by the registration number.
+func (w *watchlist) list() []WatchlistEntry {
+	entries := make([]WatchlistEntry, 0, len(w.entries))
+	for _, entry := range w.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RegNum < entries[j].RegNum })
+	return entries
+}
+
+// raise appends the alert to the log, dropping the oldest alert once the
+// log holds maxAlerts.
+func (w *watchlist) raise(alert Alert) {
+	if len(w.alerts) >= maxAlerts {
+		w.alerts = append(w.alerts[:0], w.alerts[len(w.alerts)-maxAlerts+1:]...)
+	}
+	w.alerts = append(w.alerts, alert)
+	w.raised++
+}
+
+// since returns the alerts raised after the count of the raised alerts was
+// at raised.
+func (w *watchlist) since(raised int) []Alert {
+	n := w.raised - raised
+	if n <= 0 {
+		return nil
+	}
+	return append([]Alert(nil), w.alerts[len(w.alerts)-n:]...)
+}
+
+// alertsFor returns the logged alerts of the registration number in the
+// order they were raised. All the alerts are returned if regNum is empty.
+func (w *watchlist) alertsFor(regNum string) []Alert {
+	alerts := make([]Alert, 0)
+	for _, alert := range w.alerts {
+		if regNum == "" || alert.RegNum == regNum {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// newAlert builds the alert of the watchlisted car.
+func newAlert(entry WatchlistEntry, car *dao.Car, slotID int, at time.Time) Alert {
+	return Alert{At: at, RegNum: car.RegistrationNumber, Color: car.Color, Severity: entry.Severity,
+		Reason: entry.Reason, Slot: slotID, Refused: slotID == 0}
+}
+
+// screen refuses parking to the car if it is blocked by the watchlist. The
+// refused car raises the alert.
+func (e *Executor) screen(car *dao.Car) error {
+	entry, ok := e.watchlist.lookup(car.RegistrationNumber)
+	if !ok || !e.watchlist.refuses(entry) {
+		return nil
+	}
+	e.alert(newAlert(entry, car, 0, car.ParkedAt), car)
+	return ErrRegistrationNumberBlocked.WithDetail("car %s is blocked by the watchlist: %s",
+		car.RegistrationNumber, entry.Reason)
+}
+
+// alert logs and publishes the alert of the watchlisted car.
+func (e *Executor) alert(alert Alert, car *dao.Car) {
+	e.watchlist.raise(alert)
+	if e.events != nil {
+		hit := *car
+		e.events.Publish(events.Event{Type: events.TypeWatchlistHit, At: alert.At, Slot: alert.Slot, Car: &hit,
+			Severity: string(alert.Severity), Reason: alert.Reason, Refused: alert.Refused})
+	}
+}
+
+// restoreWatchlist replaces the watchlist and the alert log with the ones
+// of the snapshot validated by normalizeSnapshot.
+func (e *Executor) restoreWatchlist(snapshot Snapshot) {
+	entries := make(map[string]WatchlistEntry, len(snapshot.Watchlist))
+	for _, entry := range snapshot.Watchlist {
+		entries[entry.RegNum] = entry
+	}
+	e.watchlist.entries = entries
+	e.watchlist.alerts = snapshot.Alerts
+}
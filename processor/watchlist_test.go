@@ -0,0 +1,160 @@
+package processor
+
+import (
+	"errors"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"parking_lot/parser"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecutor_Watchlist(t *testing.T) {
+	executor := newTestExecutor(2)
+	for _, args := range [][]string{
+		{"ka-01-hh-0001", "high", "Stolen"},
+		{"KA-01-HH-0002", "low", "Unpaid fines"},
+		{"KA-01-HH-0001", "medium", "Reported stolen"},
+	} {
+		if _, err := executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, args)); err != nil {
+			t.Fatalf("Execute(watchlist_add %v) Error %v", args, err)
+		}
+	}
+	_, err := executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0003", "urgent", "Banned"}))
+	if !errors.Is(err, ErrUnknownSeverity) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrUnknownSeverity)
+	}
+
+	result, _ := executor.Execute(parser.NewCommand(parser.CommandWatchlist, nil))
+	want := []WatchlistEntry{
+		{RegNum: "KA-01-HH-0001", Severity: SeverityMedium, Reason: "Reported stolen"},
+		{RegNum: "KA-01-HH-0002", Severity: SeverityLow, Reason: "Unpaid fines"},
+	}
+	if !reflect.DeepEqual(result.Watchlist, want) {
+		t.Errorf("Execute() got %+v want %+v", result.Watchlist, want)
+	}
+
+	if _, err := executor.Execute(parser.NewCommand(parser.CommandWatchlistRemove, []string{"KA-01-HH-0002"})); err != nil {
+		t.Errorf("Execute() Error %v", err)
+	}
+	_, err = executor.Execute(parser.NewCommand(parser.CommandWatchlistRemove, []string{"KA-01-HH-0002"}))
+	if !errors.Is(err, ErrNotWatchlisted) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrNotWatchlisted)
+	}
+
+	result = parkWithPermit(&executor, "KA-01-HH-0001", "")
+	if len(result.Alerts) != 1 || result.Alerts[0].Slot != 1 || result.Alerts[0].Severity != SeverityMedium {
+		t.Errorf("Execute() got alerts %+v", result.Alerts)
+	}
+	if got, want := FormatResult(result), "Allocated slot number: 1\nAlert: KA-01-HH-0001 is on the watchlist (medium): Reported stolen\n"; got != want {
+		t.Errorf("FormatResult() got %q want %q", got, want)
+	}
+	if result = parkWithPermit(&executor, "KA-01-HH-0002", ""); len(result.Alerts) != 0 {
+		t.Errorf("Execute() got alerts %+v want none", result.Alerts)
+	}
+}
+
+func TestExecutor_WatchlistStrict(t *testing.T) {
+	allocator := NewNearestAllocator()
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &dao.InMemoryStorage{}, WithWatchlistStrict(true),
+		WithWaitlistCapacity(1), WithClock(newTestClock(time.Minute).Now))
+	commands := []parser.Command{
+		parser.NewCommand(parser.CommandCreateParkingLot, []string{"1"}),
+		parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0001", "blocked", "Banned"}),
+		parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0002", "high", "Stolen"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0003", "White"}),
+		parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0002", "White"}),
+	}
+	for _, command := range commands {
+		if _, err := executor.Execute(command); err != nil {
+			t.Fatalf("Execute(%s) Error %v", command.Type, err)
+		}
+	}
+
+	// Blocked car is refused even if it would only be waitlisted.
+	_, err := executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}))
+	if !errors.Is(err, ErrRegistrationNumberBlocked) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrRegistrationNumberBlocked)
+	}
+	_, err = executor.Execute(parser.NewCommand(parser.CommandParkAt, []string{"1", "KA-01-HH-0001", "White"}))
+	if !errors.Is(err, ErrRegistrationNumberBlocked) {
+		t.Errorf("Execute() Error got %v want %v", err, ErrRegistrationNumberBlocked)
+	}
+
+	// Waitlisted car raises the alert once parked in the freed slot.
+	result, err := executor.Execute(parser.NewCommand(parser.CommandLeave, []string{"1"}))
+	if err != nil || result.Admitted != "KA-01-HH-0002" || len(result.Alerts) != 1 {
+		t.Errorf("Execute() got %+v, %v want KA-01-HH-0002 admitted with an alert", result, err)
+	}
+
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandAlerts, nil))
+	got := make([]string, 0)
+	for _, alert := range result.AlertLog {
+		got = append(got, alert.RegNum)
+		if alert.Refused != (alert.Slot == 0) {
+			t.Errorf("Execute() got alert %+v", alert)
+		}
+	}
+	if want := []string{"KA-01-HH-0001", "KA-01-HH-0001", "KA-01-HH-0002"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Execute() got alerts of %v want %v", got, want)
+	}
+	result, _ = executor.Execute(parser.NewCommand(parser.CommandAlerts, []string{"KA-01-HH-0002"}))
+	if len(result.AlertLog) != 1 {
+		t.Errorf("Execute() got %d alerts want %d", len(result.AlertLog), 1)
+	}
+	want := "Time                      Registration No    Slot No.    Severity    Reason\n" +
+		"2020-01-01T09:05:00Z      KA-01-HH-0002      1           high        Stolen\n"
+	if got := FormatResult(result); got != want {
+		t.Errorf("FormatResult() got %q want %q", got, want)
+	}
+}
+
+func TestExecutor_WatchlistEvents(t *testing.T) {
+	bus := events.NewBus()
+	hits := make([]events.Event, 0)
+	bus.Subscribe(func(event events.Event) { hits = append(hits, event) }, events.TypeWatchlistHit)
+	allocator := NewNearestAllocator()
+	executor := NewExecutor(&sync.Mutex{}, &allocator, &dao.InMemoryStorage{}, WithWatchlistStrict(true), WithEvents(bus))
+
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"2"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0001", "blocked", "Banned"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0002", "low", "Unpaid fines"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0001", "White"}))
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandPark, []string{"KA-01-HH-0002", "White"}))
+
+	if len(hits) != 2 {
+		t.Fatalf("Execute() published %d hits want %d", len(hits), 2)
+	}
+	if !hits[0].Refused || hits[0].Reason != "Banned" || hits[0].Car.RegistrationNumber != "KA-01-HH-0001" {
+		t.Errorf("Execute() published %+v", hits[0])
+	}
+	if hits[1].Refused || hits[1].Slot != 1 || hits[1].Severity != "low" {
+		t.Errorf("Execute() published %+v", hits[1])
+	}
+}
+
+func TestSnapshot_Watchlist(t *testing.T) {
+	executor := newTestExecutor(0)
+	_, _ = executor.Execute(parser.NewCommand(parser.CommandWatchlistAdd, []string{"KA-01-HH-0001", "high", "Stolen"}))
+	snapshot := executor.Snapshot()
+
+	restored := newTestExecutor(0)
+	if err := restored.Restore(snapshot); err != nil {
+		t.Fatalf("Restore() Error %v", err)
+	}
+	_, _ = restored.Execute(parser.NewCommand(parser.CommandCreateParkingLot, []string{"1"}))
+	if result := parkWithPermit(&restored, "KA-01-HH-0001", ""); len(result.Alerts) != 1 {
+		t.Errorf("Execute() got alerts %+v want one", result.Alerts)
+	}
+	if snapshot = restored.Snapshot(); len(snapshot.Watchlist) != 1 || len(snapshot.Alerts) != 1 {
+		t.Errorf("Snapshot() got %+v", snapshot)
+	}
+
+	snapshot.Watchlist[0].Severity = "urgent"
+	invalid := newTestExecutor(0)
+	if err := invalid.Restore(snapshot); !errors.Is(err, ErrSnapshotInvalid) {
+		t.Errorf("Restore() Error got %v want %v", err, ErrSnapshotInvalid)
+	}
+}
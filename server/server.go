@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"parking_lot/common"
+	"parking_lot/parser"
+	"parking_lot/processor"
+)
+
+// MaxRequestSize is the largest request body accepted, in bytes.
+const MaxRequestSize = 1 << 20
+
+// ErrCommandNotAllowed specifies command that can not be executed over HTTP
+// as it accesses the file system of the server.
+var ErrCommandNotAllowed = common.NewError("ERR_COMMAND_NOT_ALLOWED", common.CategoryUsage,
+	"command is not allowed over HTTP")
+
+// Handler executes commands posted over HTTP against the parking lot.
+//
+// Commands are posted to /commands in the same format as the CLI input, one
+// command per line. Commands are executed in order until the first error.
+// Response contains the formatted output of the executed commands followed
+// by the error, if any. HTTP status code of a failed request depends on the
+// category of the error.
+type Handler struct {
+	executor  *processor.Executor
+	formatter processor.Formatter
+	// OnChange is invoked after every request that executed at least one
+	// command changing the state. Used to persist the state. Optional.
+	OnChange func() error
+}
+
+// NewHandler builds and returns the Handler.
+func NewHandler(executor *processor.Executor, formatter processor.Formatter) *Handler {
+	return &Handler{
+		executor:  executor,
+		formatter: formatter,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/commands" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.ContentLength > MaxRequestSize {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Bodies without the length are cut at the limit while being read.
+	tokenizer := parser.NewTokenizer(http.MaxBytesReader(w, r.Body, MaxRequestSize))
+	out := bytes.Buffer{}
+	changed := false
+	var failure error
+	for {
+		command, err := parser.NextCommand(&tokenizer)
+		if err == io.EOF {
+			break
+		}
+		var result processor.Result
+		if err == nil && processor.AccessesFiles(command.Type) {
+			err = ErrCommandNotAllowed.WithDetail("%s is not allowed over HTTP", command.Type)
+		}
+		if err == nil {
+			changed = changed || processor.ChangesState(command.Type)
+			result, err = h.executor.Execute(command)
+		}
+		if err != nil {
+			failure = err
+			break
+		}
+		out.WriteString(h.formatter.Format(result))
+	}
+
+	if changed && h.OnChange != nil {
+		if err := h.OnChange(); err != nil && failure == nil {
+			failure = err
+		}
+	}
+
+	status := http.StatusOK
+	if failure != nil {
+		out.WriteString(h.formatter.FormatError(failure))
+		status = statusCode(failure)
+		w.Header().Set("X-Error-Code", common.CodeOf(failure))
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(out.Bytes())
+}
+
+// statusCode returns the HTTP status code for the error category.
+func statusCode(err error) int {
+	switch common.CategoryOf(err) {
+	case common.CategoryUsage, common.CategoryValidation:
+		return http.StatusBadRequest
+	case common.CategoryNotFound:
+		return http.StatusNotFound
+	case common.CategoryCapacity:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"parking_lot/dao"
+	"parking_lot/processor"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestServer(formatter processor.Formatter) *httptest.Server {
+	allocator := processor.NewNearestAllocator()
+	storage := dao.InMemoryStorage{}
+	executor := processor.NewExecutor(&sync.Mutex{}, &allocator, &storage)
+	return httptest.NewServer(NewHandler(&executor, formatter))
+}
+
+func post(t *testing.T, url string, body string) (int, string) {
+	response, err := http.Post(url+"/commands", "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post() Error %v", err)
+	}
+	defer response.Body.Close()
+	out, _ := ioutil.ReadAll(response.Body)
+	return response.StatusCode, string(out)
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	server := newTestServer(processor.TextFormatter{})
+	defer server.Close()
+
+	status, out := post(t, server.URL, "create_parking_lot 2\npark KA-01-HH-1234 White\n")
+	expected := "Created a parking lot with 2 slots\nAllocated slot number: 1\n"
+	if status != http.StatusOK || out != expected {
+		t.Errorf("ServeHTTP() got %d %q want %d %q", status, out, http.StatusOK, expected)
+	}
+
+	status, out = post(t, server.URL, "leave 1\nleave 1\npark KA-01-HH-1235 Red\n")
+	expected = "Slot number 1 is free\nERR_SLOT_NOT_OCCUPIED: slot is not occupied\n"
+	if status != http.StatusNotFound || out != expected {
+		t.Errorf("ServeHTTP() got %d %q want %d %q", status, out, http.StatusNotFound, expected)
+	}
+}
+
+func TestHandler_ServeHTTPJSON(t *testing.T) {
+	server := newTestServer(processor.JSONFormatter{})
+	defer server.Close()
+
+	status, out := post(t, server.URL, "create_parking_lot 2\npark\n")
+	expected := `{"command":"create_parking_lot","lot_size":2}` + "\n" +
+		`{"error":{"code":"ERR_INCORRECT_USAGE","category":"usage","detail":"incorrect number of arguments for the command"}}` + "\n"
+	if status != http.StatusBadRequest || out != expected {
+		t.Errorf("ServeHTTP() got %d %q want %d %q", status, out, http.StatusBadRequest, expected)
+	}
+}
+
+func TestHandler_ServeHTTPRejectsGet(t *testing.T) {
+	server := newTestServer(processor.TextFormatter{})
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/commands")
+	if err != nil {
+		t.Fatalf("Get() Error %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("ServeHTTP() got %d want %d", response.StatusCode, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestHandler_ServeHTTPRefusesFileCommands(t *testing.T) {
+	server := newTestServer(processor.TextFormatter{})
+	defer server.Close()
+
+	_, _ = post(t, server.URL, "create_parking_lot 2\n")
+	for _, body := range []string{
+		"save_snapshot /tmp/lot.json\n",
+		"export_stats /tmp/stats.csv\n",
+		"load_snapshot /etc/passwd\n",
+		"diff_snapshots /etc/passwd /etc/hosts\n",
+	} {
+		status, out := post(t, server.URL, body)
+		if status != http.StatusBadRequest || !strings.Contains(out, "ERR_COMMAND_NOT_ALLOWED") {
+			t.Errorf("ServeHTTP(%q) got %d %q want %d ERR_COMMAND_NOT_ALLOWED", body, status, out,
+				http.StatusBadRequest)
+		}
+	}
+}
+
+func TestHandler_ServeHTTPRefusesLargeBody(t *testing.T) {
+	server := newTestServer(processor.TextFormatter{})
+	defer server.Close()
+
+	body := "create_parking_lot 2\n" + strings.Repeat("status\n", MaxRequestSize/len("status\n")+1)
+	if status, _ := post(t, server.URL, body); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("ServeHTTP() got %d want %d", status, http.StatusRequestEntityTooLarge)
+	}
+}
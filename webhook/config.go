@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"parking_lot/common"
+	"parking_lot/events"
+)
+
+// ErrConfigInvalid specifies webhook configuration that can not be used.
+var ErrConfigInvalid = common.NewError("ERR_WEBHOOK_CONFIG_INVALID", common.CategoryValidation,
+	"webhook configuration is not valid")
+
+// Endpoint is an HTTP endpoint the events are posted to.
+type Endpoint struct {
+	// URL the events are posted to.
+	URL string `json:"url"`
+	// Secret the requests are signed with. Requests are not signed if empty.
+	Secret string `json:"secret,omitempty"`
+	// Events lists the names of the event types posted (Eg: car_parked).
+	// All the events are posted if empty.
+	Events []string `json:"events,omitempty"`
+
+	types map[events.Type]bool
+}
+
+// Wants returns true if the events of the type are posted to the endpoint.
+func (e *Endpoint) Wants(eventType events.Type) bool {
+	return len(e.types) == 0 || e.types[eventType]
+}
+
+// validate checks the URL and resolves the event type names.
+func (e *Endpoint) validate() error {
+	target, err := url.Parse(e.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrConfigInvalid.WithDetail("endpoint %q must be an http or https URL", e.URL)
+	}
+	e.types = make(map[events.Type]bool, len(e.Events))
+	for _, name := range e.Events {
+		var eventType events.Type
+		_ = eventType.UnmarshalText([]byte(name))
+		if eventType == events.TypeUnknown {
+			return ErrConfigInvalid.WithDetail("endpoint %q: unknown event %q", e.URL, name)
+		}
+		e.types[eventType] = true
+	}
+	return nil
+}
+
+// ReadConfig reads the JSON list of the endpoints.
+//
+//	[{"url": "https://billing.example.com/events", "secret": "...", "events": ["car_parked", "car_left"]}]
+func ReadConfig(r io.Reader) ([]Endpoint, error) {
+	endpoints := make([]Endpoint, 0)
+	if err := json.NewDecoder(r).Decode(&endpoints); err != nil {
+		return nil, ErrConfigInvalid.WithDetail("%s", err.Error())
+	}
+	seen := make(map[string]bool, len(endpoints))
+	for i := range endpoints {
+		if err := endpoints[i].validate(); err != nil {
+			return nil, err
+		}
+		if seen[endpoints[i].URL] {
+			return nil, ErrConfigInvalid.WithDetail("endpoint %q is repeated", endpoints[i].URL)
+		}
+		seen[endpoints[i].URL] = true
+	}
+	return endpoints, nil
+}
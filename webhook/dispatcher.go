@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"parking_lot/common"
+	"parking_lot/events"
+	"sync"
+	"time"
+)
+
+// ErrOutboxInvalid specifies outbox file that can not be read.
+var ErrOutboxInvalid = common.NewError("ERR_WEBHOOK_OUTBOX_INVALID", common.CategoryValidation,
+	"webhook outbox is not valid")
+
+// Headers of the posted requests.
+const (
+	// HeaderEvent is the name of the event type (Eg: car_parked).
+	HeaderEvent = "X-Parking-Lot-Event"
+	// HeaderDelivery is the ID of the event. Retries of the same delivery
+	// carry the same ID.
+	HeaderDelivery = "X-Parking-Lot-Delivery"
+	// HeaderSignature is "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// the request body keyed with the secret of the endpoint.
+	HeaderSignature = "X-Parking-Lot-Signature"
+)
+
+// Sign returns the signature of the body as sent in HeaderSignature.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// payload is the JSON body of the posted requests.
+type payload struct {
+	ID string `json:"id"`
+	events.Event
+}
+
+// Dispatcher posts the events to the endpoints. Each endpoint gets the
+// events in order, a delivery failing with a network error or with 408, 429
+// or 5xx status is retried with exponential backoff until it succeeds and
+// holds back the later ones. Deliveries rejected with other statuses are
+// dropped. Deliveries are persisted in the outbox, if any, until they
+// succeed and are resumed by the next Dispatcher using the same outbox.
+type Dispatcher struct {
+	queues         []*queue
+	outbox         outbox
+	client         *http.Client
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	logger         *log.Logger
+
+	mu       sync.Mutex
+	sequence int
+
+	// ctx is cancelled on Close to stop the delivery.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// queue holds the deliveries pending for an endpoint.
+type queue struct {
+	endpoint Endpoint
+	mu       sync.Mutex
+	pending  []delivery
+	wake     chan struct{}
+}
+
+// Option configures the optional behaviour of the Dispatcher.
+type Option func(d *Dispatcher)
+
+// WithOutbox sets the directory the pending deliveries are persisted in.
+// By default, pending deliveries are kept in memory only.
+func WithOutbox(dir string) Option {
+	return func(d *Dispatcher) {
+		d.outbox.dir = dir
+	}
+}
+
+// WithClient sets the client the requests are sent with. Defaults to a
+// client with 10 seconds timeout.
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithBackoff sets the wait before the first retry of a delivery, doubled on
+// each retry up to the maximum. Defaults to 500ms and 1 minute.
+func WithBackoff(initial time.Duration, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.initialBackoff = initial
+		d.maxBackoff = max
+	}
+}
+
+// WithLogger sets the logger the failed deliveries are reported to. By
+// default, failures are not reported.
+func WithLogger(logger *log.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// NewDispatcher builds the Dispatcher posting to the endpoints and starts
+// delivering the events left in the outbox. Deliveries in the outbox to the
+// URLs not among the endpoints are left in place.
+func NewDispatcher(endpoints []Endpoint, options ...Option) (*Dispatcher, error) {
+	d := &Dispatcher{
+		client:         &http.Client{Timeout: 10 * time.Second},
+		initialBackoff: 500 * time.Millisecond,
+		maxBackoff:     time.Minute,
+		logger:         log.New(ioutil.Discard, "", 0),
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for _, option := range options {
+		option(d)
+	}
+	byURL := make(map[string]*queue, len(endpoints))
+	for _, endpoint := range endpoints {
+		if err := endpoint.validate(); err != nil {
+			return nil, err
+		}
+		q := &queue{endpoint: endpoint, wake: make(chan struct{}, 1)}
+		d.queues = append(d.queues, q)
+		byURL[endpoint.URL] = q
+	}
+
+	deliveries, err := d.outbox.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, pending := range deliveries {
+		if q, ok := byURL[pending.URL]; ok {
+			q.push(pending)
+		} else {
+			d.logger.Printf("delivery %s to %s left in the outbox: endpoint is not configured", pending.ID, pending.URL)
+		}
+	}
+
+	for _, q := range d.queues {
+		d.workers.Add(1)
+		go d.work(q)
+	}
+	return d, nil
+}
+
+// Handle queues the event for the endpoints that want it. Meant to be
+// subscribed synchronously to the events.Bus so that the event is in the
+// outbox by the time the command returns.
+func (d *Dispatcher) Handle(event events.Event) {
+	d.mu.Lock()
+	d.sequence++
+	id := fmt.Sprintf("%019d-%06d", time.Now().UnixNano(), d.sequence%1000000)
+	d.mu.Unlock()
+
+	body, err := json.Marshal(payload{ID: id, Event: event})
+	if err != nil {
+		// Event contains only plain values. Marshalling can not fail.
+		panic(err)
+	}
+	for _, q := range d.queues {
+		if !q.endpoint.Wants(event.Type) {
+			continue
+		}
+		pending := delivery{ID: id, URL: q.endpoint.URL, Event: event.Type.String(), Payload: body}
+		if err := d.outbox.save(pending); err != nil {
+			d.logger.Printf("delivery %s to %s is not persisted: %s", id, q.endpoint.URL, err)
+		}
+		q.push(pending)
+	}
+}
+
+// Pending returns the number of the deliveries not yet acknowledged.
+func (d *Dispatcher) Pending() int {
+	pending := 0
+	for _, q := range d.queues {
+		q.mu.Lock()
+		pending += len(q.pending)
+		q.mu.Unlock()
+	}
+	return pending
+}
+
+// Close waits up to the timeout for the pending deliveries and stops the
+// delivery. Deliveries still pending stay in the outbox for the next run.
+func (d *Dispatcher) Close(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for d.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	d.cancel()
+	d.workers.Wait()
+}
+
+func (q *queue) push(pending delivery) {
+	q.mu.Lock()
+	q.pending = append(q.pending, pending)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// head returns the oldest pending delivery.
+func (q *queue) head() (delivery, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return delivery{}, false
+	}
+	return q.pending[0], true
+}
+
+func (q *queue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = q.pending[1:]
+}
+
+// work delivers the events to the endpoint in order until stopped.
+func (d *Dispatcher) work(q *queue) {
+	defer d.workers.Done()
+	for {
+		pending, ok := q.head()
+		if !ok {
+			select {
+			case <-q.wake:
+				continue
+			case <-d.ctx.Done():
+				return
+			}
+		}
+
+		backoff := d.initialBackoff
+		for attempt := 1; ; attempt++ {
+			retry, err := d.post(q.endpoint, pending)
+			if err == nil {
+				break
+			} else if !retry {
+				d.logger.Printf("delivery %s to %s dropped: %s", pending.ID, pending.URL, err)
+				break
+			}
+			d.logger.Printf("delivery %s to %s failed (attempt %d), retrying in %s: %s",
+				pending.ID, pending.URL, attempt, backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-d.ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > d.maxBackoff {
+				backoff = d.maxBackoff
+			}
+		}
+		if err := d.outbox.remove(pending); err != nil {
+			d.logger.Printf("delivery %s to %s is not removed from the outbox: %s", pending.ID, pending.URL, err)
+		}
+		q.pop()
+	}
+}
+
+// post sends the delivery to the endpoint. Returns whether the failed
+// delivery should be retried.
+func (d *Dispatcher) post(endpoint Endpoint, pending delivery) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(pending.Payload))
+	if err != nil {
+		return false, err
+	}
+	request = request.WithContext(d.ctx)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, pending.Event)
+	request.Header.Set(HeaderDelivery, pending.ID)
+	if endpoint.Secret != "" {
+		request.Header.Set(HeaderSignature, Sign(endpoint.Secret, pending.Payload))
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	response.Body.Close()
+
+	switch status := response.StatusCode; {
+	case status >= 200 && status < 300:
+		return false, nil
+	case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+		return true, fmt.Errorf("%s", response.Status)
+	default:
+		return false, fmt.Errorf("%s", response.Status)
+	}
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"parking_lot/dao"
+	"parking_lot/events"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is the local endpoint recording the requests posted to it.
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	// statuses are returned in order, 200 once they run out.
+	statuses []int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, request)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) received() ([]*http.Request, [][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*http.Request(nil), r.requests...), append([][]byte(nil), r.bodies...)
+}
+
+func parkedEvent(slot int) events.Event {
+	return events.Event{Type: events.TypeCarParked, At: time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC),
+		Slot: slot, Car: &dao.Car{RegistrationNumber: "KA-01-HH-1234", Color: "White"}}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	endpoints := []Endpoint{{URL: server.URL, Secret: "s3cret", Events: []string{"car_parked"}}}
+	dispatcher, err := NewDispatcher(endpoints)
+	if err != nil {
+		t.Fatalf("NewDispatcher() got %v", err)
+	}
+	dispatcher.Handle(events.Event{Type: events.TypeLotCreated, LotSize: 6})
+	dispatcher.Handle(parkedEvent(1))
+	dispatcher.Close(time.Second)
+
+	requests, bodies := r.received()
+	if len(requests) != 1 {
+		t.Fatalf("Handle() posted %d requests want %d", len(requests), 1)
+	}
+	if got := requests[0].Header.Get(HeaderSignature); got != Sign("s3cret", bodies[0]) {
+		t.Errorf("Handle() signature got %q want %q", got, Sign("s3cret", bodies[0]))
+	}
+	if got := requests[0].Header.Get(HeaderEvent); got != "car_parked" {
+		t.Errorf("Handle() event header got %q want %q", got, "car_parked")
+	}
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(bodies[0], &body); err != nil {
+		t.Fatalf("Unmarshal() got %v", err)
+	}
+	if body["type"] != "car_parked" || body["slot"] != 1.0 || body["id"] != requests[0].Header.Get(HeaderDelivery) {
+		t.Errorf("Handle() posted %s", bodies[0])
+	}
+}
+
+func TestDispatcher_Retry(t *testing.T) {
+	r := &receiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadRequest}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	dispatcher, err := NewDispatcher([]Endpoint{{URL: server.URL}}, WithBackoff(time.Millisecond, 2*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewDispatcher() got %v", err)
+	}
+	// First delivery succeeds on the third attempt, the second one is
+	// rejected with 400 and dropped, the third one succeeds at once.
+	for slot := 1; slot <= 3; slot++ {
+		dispatcher.Handle(parkedEvent(slot))
+	}
+	dispatcher.Close(time.Second)
+
+	requests, _ := r.received()
+	ids := make([]string, 0)
+	for _, request := range requests {
+		ids = append(ids, request.Header.Get(HeaderDelivery))
+	}
+	if len(ids) != 5 || ids[0] != ids[1] || ids[1] != ids[2] || ids[2] == ids[3] || ids[3] == ids[4] {
+		t.Errorf("Handle() posted deliveries %v", ids)
+	}
+	if dispatcher.Pending() != 0 {
+		t.Errorf("Pending() got %d want %d", dispatcher.Pending(), 0)
+	}
+}
+
+func TestDispatcher_Outbox(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatalf("TempDir() got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Receiver is down for the first run.
+	r := &receiver{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	endpoints := []Endpoint{{URL: server.URL}}
+
+	dispatcher, err := NewDispatcher(endpoints, WithOutbox(dir), WithBackoff(time.Hour, time.Hour))
+	if err != nil {
+		t.Fatalf("NewDispatcher() got %v", err)
+	}
+	for slot := 1; slot <= 3; slot++ {
+		dispatcher.Handle(parkedEvent(slot))
+	}
+	dispatcher.Close(50 * time.Millisecond)
+	if files, _ := ioutil.ReadDir(dir); len(files) != 3 {
+		t.Fatalf("outbox holds %d files want %d", len(files), 3)
+	}
+
+	// Next run delivers the events left in the outbox in order.
+	r.mu.Lock()
+	r.statuses = nil
+	r.mu.Unlock()
+	dispatcher, err = NewDispatcher(endpoints, WithOutbox(dir))
+	if err != nil {
+		t.Fatalf("NewDispatcher() got %v", err)
+	}
+	dispatcher.Close(time.Second)
+
+	requests, bodies := r.received()
+	slots := make([]int, 0)
+	for _, body := range bodies[1:] {
+		event := events.Event{}
+		_ = json.Unmarshal(body, &event)
+		slots = append(slots, event.Slot)
+	}
+	if want := []int{1, 2, 3}; len(requests) != 4 || !reflect.DeepEqual(slots, want) {
+		t.Errorf("NewDispatcher() posted %d requests for slots %v want 4 for %v", len(requests), slots, want)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("outbox holds %d files want %d", len(files), 0)
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	endpoints, err := ReadConfig(strings.NewReader(`[{"url": "https://example.com/events", "events": ["car_left"]}]`))
+	if err != nil {
+		t.Fatalf("ReadConfig() got %v", err)
+	}
+	if !endpoints[0].Wants(events.TypeCarLeft) || endpoints[0].Wants(events.TypeCarParked) {
+		t.Errorf("ReadConfig() got %+v want car_left only", endpoints[0])
+	}
+
+	for _, config := range []string{
+		`[{"url": "ftp://example.com/events"}]`,
+		`[{"url": "https://example.com/events", "events": ["car_towed"]}]`,
+		`[{"url": "https://example.com/events"}, {"url": "https://example.com/events"}]`,
+		`{"url": "https://example.com/events"}`,
+	} {
+		if _, err := ReadConfig(strings.NewReader(config)); !errors.Is(err, ErrConfigInvalid) {
+			t.Errorf("ReadConfig(%s) got %v want %v", config, err, ErrConfigInvalid)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// delivery is an event to be posted to an endpoint.
+type delivery struct {
+	// ID identifies the event. Deliveries of the same event to different
+	// endpoints share the ID. IDs sort in the order of the events.
+	ID      string          `json:"id"`
+	URL     string          `json:"url"`
+	Event   string          `json:"event"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// outbox persists the deliveries until the endpoints acknowledge them, one
+// file per delivery. Deliveries are kept in memory only if the directory
+// is not set.
+type outbox struct {
+	dir string
+}
+
+// path returns the file of the delivery. Files sort in the order of the
+// events.
+func (o outbox) path(d delivery) string {
+	hash := sha1.Sum([]byte(d.URL))
+	return filepath.Join(o.dir, d.ID+"-"+hex.EncodeToString(hash[:4])+".json")
+}
+
+// save writes the delivery. The file is written in full or not at all.
+func (o outbox) save(d delivery) error {
+	if o.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	path := o.path(d)
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// remove deletes the acknowledged delivery.
+func (o outbox) remove(d delivery) error {
+	if o.dir == "" {
+		return nil
+	}
+	if err := os.Remove(o.path(d)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// load reads the deliveries left by the earlier runs in the order of the
+// events. Creates the directory if it does not exist.
+func (o outbox) load() ([]delivery, error) {
+	if o.dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(o.dir, 0700); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	deliveries := make([]delivery, 0, len(names))
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(o.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		d := delivery{}
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, ErrOutboxInvalid.WithDetail("outbox file %s: %s", name, err.Error())
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}